@@ -12,6 +12,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,114 +21,435 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mholt/archiver/v3"
 )
 
 // Mirrors the Adoptium "Release" schema
 type adoptiumRelease struct {
-	Binaries []adoptiumBinary `json:"binaries"`
-	Binary   adoptiumBinary   `json:"binary"`
+	Binaries    []adoptiumBinary `json:"binaries"`
+	Binary      adoptiumBinary   `json:"binary"`
+	ReleaseName string           `json:"release_name"`
+}
+
+// Mirrors the Adoptium "ReleaseInfo" schema
+type adoptiumReleaseInfo struct {
+	MostRecentFeatureRelease int `json:"most_recent_feature_release"`
+	MostRecentFeatureVersion int `json:"most_recent_feature_version"`
+	MostRecentLts            int `json:"most_recent_lts"`
 }
 
 // Mirrors the Adoptium "Binary" schema
 type adoptiumBinary struct {
-	Architecture   string          `json:"architecture" binding:"required"`
-	Implementation string          `json:"jvm_impl" binding:"required"`
-	Platform       string          `json:"os" binding:"required"`
-	Package        adoptiumPackage `json:"package" binding:"required"`
+	Architecture   string     `json:"architecture" binding:"required"`
+	Implementation string     `json:"jvm_impl" binding:"required"`
+	Platform       string     `json:"os" binding:"required"`
+	Package        jdkPackage `json:"package" binding:"required"`
 }
 
-// Mirrors the Adoptium "Package" schema
-type adoptiumPackage struct {
-	Name string `json:"name" binding:"required"`
-	Link string `json:"link" binding:"required"`
+// Release describes the binary as a JDK release of the given vendor, version
+// and release type.
+func (binary *adoptiumBinary) release(vendor, version, releaseType string) *jdkRelease {
+	return &jdkRelease{
+		Vendor:         vendor,
+		Architecture:   binary.Architecture,
+		Platform:       binary.Platform,
+		Implementation: binary.Implementation,
+		Version:        version,
+		ReleaseType:    releaseType,
+		Package:        binary.Package,
+	}
+}
+
+// AdoptiumVersion converts a release name like jdk-17.0.1+12 or an early-access
+// release name like jdk-23+10-ea-beta to a version like 17.0.1+12 or 23-ea+10.
+func adoptiumVersion(releaseName, releaseType string) (string, error) {
+	version := strings.TrimPrefix(releaseName, "jdk-")
+	if releaseType == "ea" {
+		number, build, _ := strings.Cut(version, "+")
+		number, _, _ = strings.Cut(number, "-")
+		build, _, _ = strings.Cut(build, "-")
+
+		version = number + "-ea"
+		if build != "" {
+			version += "+" + build
+		}
+	}
+
+	if !versionCheck.MatchString(version) && !eaVersionCheck.MatchString(version) {
+		return "", errors.New("Unexpected release name: " + releaseName)
+	}
+	return version, nil
 }
 
-// Only one runtime can be downloaded at a time. This is to prevent issues with
-// partial downloads.
-var downloadLock sync.Mutex
+// AdoptiumProvider provides JDKs from an API compatible with the Adoptium v3 API.
+type adoptiumProvider struct {
 
-// A local cache for runtime information which never changes
-var metadataCache map[string]*adoptiumBinary = make(map[string]*adoptiumBinary)
+	// The vendor name of the JDKs
+	vendor string
 
-// LookupRelease finds release metadata for the given attributes.
-func lookupRelease(arch, platform, implementation, version string) (*adoptiumBinary, error) {
+	// The base URL of the API
+	api string
+}
+
+// RuntimeDownload is an in-flight download of a runtime package which
+// concurrent requests for the same package wait for.
+type runtimeDownload struct {
+	done chan struct{}
+	err  error
+}
+
+// The in-flight runtime downloads by cache directory
+var runtimeDownloads = struct {
+	sync.Mutex
+	inFlight map[string]*runtimeDownload
+}{inFlight: make(map[string]*runtimeDownload)}
+
+// Lookup finds release metadata for the given attributes.
+func (provider *adoptiumProvider) lookup(arch, platform, implementation, version, releaseType string) (*jdkRelease, error) {
+	if isVersionAlias(version) {
+		return provider.resolve(arch, platform, implementation, version, releaseType)
+	}
+
+	cacheKey := provider.vendor + "_" + arch + "_" + platform + "_" + implementation + "_" + version + "_" + releaseType
+	var binary adoptiumBinary
+
+	// Only local content can be used in offline mode, but cached metadata is
+	// still useful for runtimes which are already in RT_CACHE
+	if OFFLINE {
+		offline, err := lookupOfflineRelease(provider.vendor, arch, platform, implementation, version, releaseType)
+		if err != nil && metadataCache.get(metadataRelease, cacheKey, &binary) {
+			return binary.release(provider.vendor, version, releaseType), nil
+		}
+		return offline, err
+	}
 
 	// Check cache first
-	cacheKey := arch + "_" + platform + "_" + implementation + "_" + version
-	if binary := metadataCache[cacheKey]; binary != nil {
-		return binary, nil
+	if metadataCache.get(metadataRelease, cacheKey, &binary) {
+		return binary.release(provider.vendor, version, releaseType), nil
 	}
 
-	url := fmt.Sprintf("https://api.adoptopenjdk.net/v3/assets/version/%s?jvm_impl=%s&os=%s&architecture=%s", version, implementation, platform, arch)
-	log.Println("METADATA QUERY:", url)
-	res, err := adoptium.Get(url)
+	var missing bool
+	if metadataCache.get(metadataMissing, cacheKey, &missing) {
+		return nil, errors.New("No release found")
+	}
+
+	releases, err := provider.query(fmt.Sprintf("%s/v3/assets/version/%s?jvm_impl=%s&os=%s&architecture=%s&release_type=%s", provider.api, version, implementation, platform, arch, releaseType))
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	var releases []adoptiumRelease
-	if err := json.NewDecoder(res.Body).Decode(&releases); err != nil {
+	if len(releases) > 0 && len(releases[0].Binaries) > 0 {
+		// Update cache
+		if err := metadataCache.put(metadataRelease, cacheKey, releases[0].Binaries[0]); err != nil {
+			log.Println(err)
+		}
+		return releases[0].Binaries[0].release(provider.vendor, version, releaseType), nil
+	}
+
+	if err := metadataCache.put(metadataMissing, cacheKey, true); err != nil {
+		log.Println(err)
+	}
+	return nil, errors.New("No release found")
+}
+
+// Resolve finds the newest release of the given type for a version alias like
+// latest, lts, 17 or 17-latest. The version of the returned release is the
+// exact version.
+func (provider *adoptiumProvider) resolve(arch, platform, implementation, alias, releaseType string) (*jdkRelease, error) {
+
+	cacheKey := provider.vendor + "_" + arch + "_" + platform + "_" + implementation + "_" + alias + "_" + releaseType
+	var release jdkRelease
+
+	// Resolve aliases among the local releases in offline mode
+	if OFFLINE {
+		offline, err := lookupOfflineAlias(provider.vendor, arch, platform, implementation, alias, releaseType)
+		if err == nil {
+			return offline, nil
+		}
+		if metadataCache.get(metadataAlias, cacheKey, &release) {
+			return &release, nil
+		}
+		return nil, err
+	}
+
+	// Check cache first
+	if metadataCache.get(metadataAlias, cacheKey, &release) {
+		return &release, nil
+	}
+
+	var missing bool
+	if metadataCache.get(metadataMissing, cacheKey, &missing) {
+		return nil, errors.New("No release found")
+	}
+
+	feature, err := provider.feature(alias, releaseType)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := provider.query(fmt.Sprintf("%s/v3/assets/feature_releases/%d/%s?jvm_impl=%s&os=%s&architecture=%s&image_type=jdk&page_size=1&sort_order=DESC",
+		provider.api, feature, releaseType, implementation, platform, arch))
+	if err != nil {
 		return nil, err
 	}
 
 	if len(releases) > 0 && len(releases[0].Binaries) > 0 {
-		// Update cache
-		metadataCache[cacheKey] = &releases[0].Binaries[0]
-		return metadataCache[cacheKey], nil
+		version, err := adoptiumVersion(releases[0].ReleaseName, releaseType)
+		if err != nil {
+			return nil, err
+		}
+
+		// Update cache for the alias and the exact version
+		binary := releases[0].Binaries[0]
+		if err := metadataCache.put(metadataRelease, provider.vendor+"_"+arch+"_"+platform+"_"+implementation+"_"+version+"_"+releaseType, binary); err != nil {
+			log.Println(err)
+		}
+		if err := metadataCache.put(metadataAlias, cacheKey, binary.release(provider.vendor, version, releaseType)); err != nil {
+			log.Println(err)
+		}
+		return binary.release(provider.vendor, version, releaseType), nil
 	}
 
+	if err := metadataCache.put(metadataMissing, cacheKey, true); err != nil {
+		log.Println(err)
+	}
 	return nil, errors.New("No release found")
 }
 
+// Feature returns the feature version which a version alias refers to. The
+// latest early-access release may be newer than the latest GA release.
+func (provider *adoptiumProvider) feature(alias, releaseType string) (int, error) {
+	if feature := aliasFeature(alias); feature != 0 {
+		return feature, nil
+	}
+
+	cacheKey := provider.vendor + "_available_releases"
+	var info adoptiumReleaseInfo
+	if !metadataCache.get(metadataAlias, cacheKey, &info) {
+		url := provider.api + "/v3/info/available_releases"
+		log.Println("METADATA QUERY:", url)
+		res, err := adoptium.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return 0, errors.New("Abnormal HTTP status code: " + res.Status)
+		}
+		if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+			return 0, err
+		}
+
+		if err := metadataCache.put(metadataAlias, cacheKey, info); err != nil {
+			log.Println(err)
+		}
+	}
+
+	feature := info.MostRecentFeatureRelease
+	if releaseType == "ea" {
+		feature = info.MostRecentFeatureVersion
+	}
+	if alias == "lts" {
+		feature = info.MostRecentLts
+	}
+	if feature == 0 {
+		return 0, errors.New("No release found")
+	}
+	return feature, nil
+}
+
+// Query fetches a list of releases from the API. A missing release isn't an error.
+func (provider *adoptiumProvider) query(url string) ([]adoptiumRelease, error) {
+	log.Println("METADATA QUERY:", url)
+	res, err := adoptium.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var releases []adoptiumRelease
+	switch res.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(res.Body).Decode(&releases); err != nil {
+			return nil, err
+		}
+	case http.StatusNotFound:
+	default:
+		return nil, errors.New("Abnormal HTTP status code: " + res.Status)
+	}
+
+	return releases, nil
+}
+
+// Download fetches a JDK package into the cache directory.
+func (provider *adoptiumProvider) download(release *jdkRelease) (string, func(), error) {
+	return downloadRelease(release)
+}
+
 // DownloadRelease downloads a runtime image to the cache directory and returns
-// the path to the extracted runtime directory.
-func downloadRelease(binary *adoptiumBinary, version string) (string, error) {
-	downloadLock.Lock()
-	defer downloadLock.Unlock()
+// the path to the extracted runtime directory. Concurrent requests for the same
+// package share a single download. The runtime can't be evicted from the cache
+// until the returned release function is called.
+func downloadRelease(jdk *jdkRelease) (string, func(), error) {
+	name := strings.TrimSuffix(strings.TrimSuffix(jdk.Package.Name, ".zip"), ".tar.gz")
+	runtimePath := RT_CACHE + string(os.PathSeparator) + jdk.Vendor + "-" + name
+
+	release := acquireRuntime(runtimePath)
+
+	runtimeDownloads.Lock()
+	if download, exists := runtimeDownloads.inFlight[runtimePath]; exists {
+		runtimeDownloads.Unlock()
+
+		// Wait for the other request's download
+		<-download.done
+		if download.err != nil {
+			release()
+			return "", nil, download.err
+		}
+		return runtimeHome(runtimePath, jdk.Version), release, nil
+	}
+
+	// Check if the runtime is cached (only once no download is in progress)
+	if runtimeComplete(runtimePath) {
+		runtimeDownloads.Unlock()
+		touchRuntime(runtimePath)
+		return runtimeHome(runtimePath, jdk.Version), release, nil
+	}
 
-	runtimePath := RT_CACHE + string(os.PathSeparator) + strings.TrimSuffix(strings.TrimSuffix(binary.Package.Name, ".zip"), ".tar.gz")
+	download := &runtimeDownload{done: make(chan struct{})}
+	runtimeDownloads.inFlight[runtimePath] = download
+	runtimeDownloads.Unlock()
 
-	// Check if the runtime is cached first
-	if _, e := os.Stat(runtimePath); !os.IsNotExist(e) {
-		return filepath.FromSlash(runtimePath + "/jdk-" + version), nil
+	download.err = fetchRelease(jdk, runtimePath)
+
+	runtimeDownloads.Lock()
+	delete(runtimeDownloads.inFlight, runtimePath)
+	runtimeDownloads.Unlock()
+	close(download.done)
+
+	if download.err != nil {
+		release()
+		return "", nil, download.err
 	}
 
-	archivePath, dir := newTemporaryFile(binary.Package.Name)
+	// Make room for the new runtime
+	if err := evictRuntimes(); err != nil {
+		log.Println(err)
+	}
+	return runtimeHome(runtimePath, jdk.Version), release, nil
+}
+
+// FetchRelease downloads a runtime package, verifies it and extracts it to the
+// given directory.
+func fetchRelease(jdk *jdkRelease, runtimePath string) error {
+	archivePath, dir := newTemporaryFile(jdk.Package.Name)
 	defer os.RemoveAll(dir)
 
 	// Download the runtime
-	log.Println("RUNTIME QUERY:", binary.Package.Link)
-	response, err := adoptium.Get(binary.Package.Link)
+	log.Println("RUNTIME QUERY:", jdk.Package.Link)
+	response, err := adoptium.Get(jdk.Package.Link)
 	if err != nil {
-		return "", err
+		return err
 	}
+	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
-		return "", errors.New("Abnormal HTTP status code: " + response.Status)
+		return errors.New("Abnormal HTTP status code: " + response.Status)
 	}
-	defer response.Body.Close()
 
 	out, err := os.Create(archivePath)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, response.Body)
+	digest := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, digest), response.Body)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	// Extract to the cache directory
-	if err := archiver.Unarchive(archivePath, runtimePath); err != nil {
-		os.RemoveAll(runtimePath)
-		return "", err
+	// Never cache a runtime which doesn't match its checksum
+	checksum := hex.EncodeToString(digest.Sum(nil))
+	if err := verifyPackage(jdk.Package, checksum); err != nil {
+		return err
+	}
+
+	// Extract to a staging directory so the runtime only appears in the cache
+	// once it's complete
+	staging, err := os.MkdirTemp(RT_CACHE, runtimeStagingPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := archiver.Unarchive(archivePath, staging); err != nil {
+		return err
+	}
+	if err := makeToolsExecutable(staging); err != nil {
+		return err
+	}
+
+	size, err := directorySize(staging)
+	if err != nil {
+		return err
+	}
+
+	if err := writeRuntimeManifest(staging, runtimeManifest{
+		Package:        jdk.Package.Name,
+		Link:           jdk.Package.Link,
+		Checksum:       checksum,
+		Vendor:         jdk.Vendor,
+		Architecture:   jdk.Architecture,
+		Platform:       jdk.Platform,
+		Implementation: jdk.Implementation,
+		Version:        jdk.Version,
+		ReleaseType:    jdk.ReleaseType,
+		Size:           size,
+		Extracted:      time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	// Replace any incomplete runtime left behind by a crash
+	if err := os.RemoveAll(runtimePath); err != nil {
+		return err
+	}
+	return os.Rename(staging, runtimePath)
+}
+
+// VerifyPackage compares the SHA-256 checksum of a downloaded package with the
+// checksum published by Adoptium.
+func verifyPackage(pkg jdkPackage, actual string) error {
+	expected := strings.ToLower(pkg.Checksum)
+	if expected == "" && pkg.ChecksumLink != "" {
+		response, err := adoptium.Get(pkg.ChecksumLink)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return errors.New("Abnormal HTTP status code: " + response.Status)
+		}
+
+		contents, err := io.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		expected = parseChecksum(string(contents))
+	}
+
+	if expected == "" {
+		log.Println("No checksum found for runtime:", pkg.Name)
+		return nil
+	}
+	if expected != actual {
+		return &checksumError{"Checksum mismatch for runtime " + pkg.Name}
 	}
 
-	return filepath.FromSlash(runtimePath + "/jdk-" + version), nil
+	return nil
 }
@@ -0,0 +1,204 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a fake JDK 11 package in zip format along with its SHA-256 checksum
+func newRuntimePackage(t *testing.T) ([]byte, string) {
+	source := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(source, "jdk-11", "bin"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(source, "jdk-11", "release"), []byte("JAVA_VERSION=\"11\""), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(source, "jdk-11", "bin", "jlink"), []byte("jlink"), 0644))
+	packagePath := filepath.Join(t.TempDir(), "jdk.zip")
+	assert.NoError(t, archiver.Archive([]string{filepath.Join(source, "jdk-11")}, packagePath))
+
+	contents, err := os.ReadFile(packagePath)
+	assert.NoError(t, err)
+	sum := sha256.Sum256(contents)
+	return contents, hex.EncodeToString(sum[:])
+}
+
+func TestDownloadRelease(t *testing.T) {
+	defer func(cache string) { RT_CACHE = cache }(RT_CACHE)
+	RT_CACHE = t.TempDir()
+
+	contents, checksum := newRuntimePackage(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jdk.zip":
+			w.Write(contents)
+		case "/jdk.zip.sha256.txt":
+			w.Write([]byte(checksum + "  jdk.zip\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// A mismatched checksum isn't cached
+	_, _, err := downloadRelease(&jdkRelease{Vendor: "adoptopenjdk", Version: "11", Package: jdkPackage{Name: "bad-jdk.zip", Link: server.URL + "/jdk.zip", Checksum: "0000"}})
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(RT_CACHE, "adoptopenjdk-bad-jdk"))
+
+	// An incomplete runtime is replaced
+	assert.NoError(t, os.MkdirAll(filepath.Join(RT_CACHE, "adoptopenjdk-jdk", "jdk-11"), os.ModePerm))
+
+	// The checksum can be given directly
+	path, _, err := downloadRelease(&jdkRelease{Vendor: "adoptopenjdk", Version: "11", Package: jdkPackage{Name: "jdk.zip", Link: server.URL + "/jdk.zip", Checksum: checksum}})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(path, "release"))
+	assert.True(t, runtimeComplete(filepath.Join(RT_CACHE, "adoptopenjdk-jdk")))
+
+	// Tools are executable even if the package lost their permissions
+	info, err := os.Stat(filepath.Join(path, "bin", "jlink"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Or by a link
+	path, _, err = downloadRelease(&jdkRelease{Vendor: "adoptopenjdk", Version: "11", Package: jdkPackage{Name: "linked-jdk.zip", Link: server.URL + "/jdk.zip", ChecksumLink: server.URL + "/jdk.zip.sha256.txt"}})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(path, "release"))
+}
+
+func TestDownloadReleaseConcurrently(t *testing.T) {
+	defer func(cache string) { RT_CACHE = cache }(RT_CACHE)
+	RT_CACHE = t.TempDir()
+
+	contents, checksum := newRuntimePackage(t)
+
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/slow.zip" {
+			<-release
+		}
+		w.Write(contents)
+	}))
+	defer server.Close()
+
+	cached := &jdkRelease{Vendor: "adoptopenjdk", Version: "11", Package: jdkPackage{Name: "cached.zip", Link: server.URL + "/cached.zip", Checksum: checksum}}
+	_, _, err := downloadRelease(cached)
+	assert.NoError(t, err)
+
+	// Concurrent requests for the same package share one download
+	slow := &jdkRelease{Vendor: "adoptopenjdk", Version: "11", Package: jdkPackage{Name: "slow.zip", Link: server.URL + "/slow.zip", Checksum: checksum}}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, _, err := downloadRelease(slow)
+			assert.NoError(t, err)
+			assert.FileExists(t, filepath.Join(path, "release"))
+		}()
+	}
+
+	// Cache hits don't wait for unrelated downloads
+	for requests.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	_, _, err = downloadRelease(cached)
+	assert.NoError(t, err)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestResolveVersionAlias(t *testing.T) {
+	defer func(metadata *metadataStore) { metadataCache = metadata }(metadataCache)
+	metadataCache = newMetadataStore("", defaultMetadataTTL)
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path)
+		switch r.URL.Path {
+		case "/v3/info/available_releases":
+			w.Write([]byte(`{"available_releases": [8, 11, 17, 18], "most_recent_feature_release": 18, "most_recent_feature_version": 19, "most_recent_lts": 17}`))
+		case "/v3/assets/feature_releases/17/ga":
+			w.Write([]byte(`[{"release_name": "jdk-17.0.2+8", "binaries": [{"architecture": "x64", "os": "linux", "jvm_impl": "hotspot", "package": {"name": "jdk17.tar.gz"}}]}]`))
+		case "/v3/assets/feature_releases/18/ga":
+			w.Write([]byte(`[{"release_name": "jdk-18+36", "binaries": [{"architecture": "x64", "os": "linux", "jvm_impl": "hotspot", "package": {"name": "jdk18.tar.gz"}}]}]`))
+		case "/v3/assets/feature_releases/19/ea":
+			w.Write([]byte(`[{"release_name": "jdk-19+5-ea-beta", "binaries": [{"architecture": "x64", "os": "linux", "jvm_impl": "hotspot", "package": {"name": "jdk19.tar.gz"}}]}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := &adoptiumProvider{vendor: "adoptopenjdk", api: server.URL}
+
+	jdk, err := provider.lookup("x64", "linux", "hotspot", "lts", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.0.2+8", jdk.Version)
+	assert.Equal(t, "jdk17.tar.gz", jdk.Package.Name)
+
+	jdk, err = provider.lookup("x64", "linux", "hotspot", "latest", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "18+36", jdk.Version)
+
+	jdk, err = provider.lookup("x64", "linux", "hotspot", "17-latest", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.0.2+8", jdk.Version)
+
+	// Aliases, release info and the exact versions are cached
+	queries = nil
+	_, err = provider.lookup("x64", "linux", "hotspot", "17", "ga")
+	assert.NoError(t, err)
+	_, err = provider.lookup("x64", "linux", "hotspot", "17.0.2+8", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/v3/assets/feature_releases/17/ga"}, queries)
+
+	_, err = provider.lookup("x64", "linux", "hotspot", "16", "ga")
+	assert.EqualError(t, err, "No release found")
+
+	// The latest early-access release may be newer
+	jdk, err = provider.lookup("x64", "linux", "hotspot", "latest", "ea")
+	assert.NoError(t, err)
+	assert.Equal(t, "19-ea+5", jdk.Version)
+	assert.Equal(t, "ea", jdk.ReleaseType)
+}
+
+func TestAdoptiumVersion(t *testing.T) {
+	version, err := adoptiumVersion("jdk-17.0.1+12", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.0.1+12", version)
+
+	version, err = adoptiumVersion("jdk-23+10-ea-beta", "ea")
+	assert.NoError(t, err)
+	assert.Equal(t, "23-ea+10", version)
+
+	version, err = adoptiumVersion("jdk-21.0.3+6-ea-beta", "ea")
+	assert.NoError(t, err)
+	assert.Equal(t, "21.0.3-ea+6", version)
+
+	_, err = adoptiumVersion("jdk8u302-b08", "ga")
+	assert.EqualError(t, err, "Unexpected release name: jdk8u302-b08")
+}
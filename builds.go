@@ -0,0 +1,156 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// The stages of a build job
+const (
+	buildQueued      = "queued"
+	buildDownloading = "downloading"
+	buildLinking     = "linking"
+	buildArchiving   = "archiving"
+	buildDone        = "done"
+	buildFailed      = "failed"
+)
+
+// BuildJob represents a runtime request that is processed in the background.
+type buildJob struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Reason   string     `json:"reason,omitempty"`
+	Version  string     `json:"version,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// The request that started the job
+	request runtimeRequest
+
+	// The finished archive
+	archive *runtimeArchive
+}
+
+// All known build jobs indexed by ID
+var builds = struct {
+	sync.Mutex
+	jobs map[string]*buildJob
+}{jobs: make(map[string]*buildJob)}
+
+// Build jobs waiting for a worker
+var buildQueue = make(chan *buildJob, 100)
+
+// SubmitBuild queues a validated runtime request and returns the new job.
+func submitBuild(req runtimeRequest) (*buildJob, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	job := &buildJob{
+		ID:      hex.EncodeToString(id),
+		Status:  buildQueued,
+		Created: time.Now(),
+		request: req,
+	}
+
+	select {
+	case buildQueue <- job:
+	default:
+		return nil, errors.New("Too many queued builds")
+	}
+
+	builds.Lock()
+	builds.jobs[job.ID] = job
+	builds.Unlock()
+
+	return job, nil
+}
+
+// LookupBuild returns a snapshot of the build job with the given ID.
+func lookupBuild(id string) (buildJob, bool) {
+	builds.Lock()
+	defer builds.Unlock()
+
+	if job, exists := builds.jobs[id]; exists {
+		return *job, true
+	}
+	return buildJob{}, false
+}
+
+// SetStatus updates the stage of a build job.
+func (job *buildJob) setStatus(status string) {
+	builds.Lock()
+	defer builds.Unlock()
+
+	job.Status = status
+}
+
+// Run executes the build pipeline for a job and stores the resulting archive.
+// The pipeline works on a copy of the request since jobs are read while they
+// run.
+func (job *buildJob) run() {
+	req := job.request
+	req.Modules = append([]string{}, job.request.Modules...)
+	archive, err := buildRuntime(&req, job.setStatus)
+
+	builds.Lock()
+	defer builds.Unlock()
+
+	finished := time.Now()
+	job.Finished = &finished
+	if err != nil {
+		job.Status = buildFailed
+		job.Reason = err.Error()
+		return
+	}
+
+	job.Status = buildDone
+	job.Version = archive.Version
+	job.archive = archive
+}
+
+// StartBuildWorkers starts the given number of workers for queued build jobs
+// and removes finished jobs once they are older than BUILD_RETENTION.
+func startBuildWorkers(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range buildQueue {
+				job.run()
+			}
+		}()
+	}
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			expireBuilds(time.Now().Add(-BUILD_RETENTION))
+		}
+	}()
+}
+
+// ExpireBuilds removes jobs that finished before the given time. Their archives
+// remain in the archive cache.
+func expireBuilds(before time.Time) {
+	builds.Lock()
+	defer builds.Unlock()
+
+	for id, job := range builds.jobs {
+		if job.Finished != nil && job.Finished.Before(before) {
+			delete(builds.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireBuilds(t *testing.T) {
+	finished := time.Now().Add(-2 * time.Hour)
+	builds.Lock()
+	builds.jobs["expired"] = &buildJob{ID: "expired", Status: buildDone, Finished: &finished}
+	builds.jobs["running"] = &buildJob{ID: "running", Status: buildLinking}
+	builds.Unlock()
+
+	job, exists := lookupBuild("expired")
+	assert.True(t, exists)
+	assert.Equal(t, buildDone, job.Status)
+
+	expireBuilds(time.Now().Add(-time.Hour))
+
+	_, exists = lookupBuild("expired")
+	assert.False(t, exists)
+
+	job, exists = lookupBuild("running")
+	assert.True(t, exists)
+	assert.Equal(t, buildLinking, job.Status)
+
+	builds.Lock()
+	delete(builds.jobs, "running")
+	builds.Unlock()
+}
+
+// A provider whose downloads wait until they're released
+type slowProvider struct {
+	downloading chan struct{}
+	release     chan struct{}
+}
+
+func (provider *slowProvider) lookup(arch, platform, implementation, version, releaseType string) (*jdkRelease, error) {
+	return &jdkRelease{Vendor: "slow", Architecture: arch, Platform: platform, Implementation: implementation, Version: version, ReleaseType: releaseType, Package: jdkPackage{Name: "jdk.tar.gz"}}, nil
+}
+
+func (provider *slowProvider) download(release *jdkRelease) (string, func(), error) {
+	provider.downloading <- struct{}{}
+	<-provider.release
+	return "", nil, errors.New("Download failed")
+}
+
+func TestRunBuild(t *testing.T) {
+	defer func(cache string) { ARCHIVE_CACHE = cache }(ARCHIVE_CACHE)
+	ARCHIVE_CACHE = t.TempDir()
+
+	provider := &slowProvider{downloading: make(chan struct{}), release: make(chan struct{})}
+	defer func(providers map[string]jdkProvider) { jdkProviders = providers }(jdkProviders)
+	jdkProviders = map[string]jdkProvider{"slow": provider}
+
+	req := runtimeRequest{Arch: "x64", Platform: "linux", Version: "17.0.1+12", Vendor: "slow", Implementation: "hotspot", ReleaseType: "ga", Modules: []string{"java.base"}, Options: defaultJlinkOptions()}
+	job := &buildJob{ID: "slow", Status: buildQueued, Created: time.Now(), request: req}
+	builds.Lock()
+	builds.jobs[job.ID] = job
+	builds.Unlock()
+	defer func() {
+		builds.Lock()
+		delete(builds.jobs, job.ID)
+		builds.Unlock()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		job.run()
+		close(done)
+	}()
+
+	// Jobs can be polled while they're running
+	<-provider.downloading
+	snapshot, exists := lookupBuild(job.ID)
+	assert.True(t, exists)
+	assert.Equal(t, buildDownloading, snapshot.Status)
+	assert.Equal(t, "", snapshot.request.Format)
+	close(provider.release)
+	<-done
+
+	snapshot, _ = lookupBuild(job.ID)
+	assert.Equal(t, buildFailed, snapshot.Status)
+	assert.Equal(t, "Failed to download local runtime", snapshot.Reason)
+}
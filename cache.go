@@ -0,0 +1,244 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RuntimeArchive represents a finished runtime archive in the archive cache.
+type runtimeArchive struct {
+
+	// The path to the archive
+	Path string
+
+	// The filename to use when downloading the archive
+	Filename string
+
+	// Whether the archive was already cached before the request
+	Cached bool
+
+	// The exact Java version of the runtime
+	Version string
+}
+
+// CacheStatus returns the value of the X-Cache response header for the archive.
+func (archive *runtimeArchive) cacheStatus() string {
+	if archive.Cached {
+		return "HIT"
+	}
+	return "MISS"
+}
+
+// NormalizeModules returns the sorted set of modules including java.base.
+func normalizeModules(modules []string) []string {
+	set := map[string]bool{"java.base": true}
+	for _, m := range modules {
+		set[m] = true
+	}
+
+	normalized := make([]string, 0, len(set))
+	for m := range set {
+		normalized = append(normalized, m)
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+// ArchiveKey returns a hash of everything that determines the contents of the
+// archive produced for a request: the target package, modules, endian type,
+// implementation, jlink options, launchers, archive format and the resolved
+// artifacts in the given directory.
+func archiveKey(packageName string, req *runtimeRequest, artifacts string) (string, error) {
+	hash := sha256.New()
+
+	fmt.Fprintln(hash, packageName)
+	fmt.Fprintln(hash, strings.Join(normalizeModules(req.Modules), ","))
+	fmt.Fprintln(hash, req.Endian)
+	fmt.Fprintln(hash, req.Implementation)
+	fmt.Fprintln(hash, strings.Join(req.Options.args(), " "))
+	fmt.Fprintln(hash, req.Format)
+
+	launchers := append([]string(nil), req.Launchers...)
+	sort.Strings(launchers)
+	fmt.Fprintln(hash, strings.Join(launchers, ","))
+	fmt.Fprintln(hash, req.ArtifactModules)
+
+	// Walk returns files in lexical order
+	err := filepath.Walk(artifacts, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fmt.Fprintln(hash, info.Name(), strconv.FormatInt(info.Size(), 10))
+		_, err = io.Copy(hash, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// LookupArchive finds a cached archive for the given key.
+func lookupArchive(key, filename string) (*runtimeArchive, bool) {
+	path := filepath.Join(ARCHIVE_CACHE, key, filename)
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+
+	// Mark the archive as recently used
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return &runtimeArchive{Path: path, Filename: filename, Cached: true}, true
+}
+
+// StoreArchive generates an archive in the cache under the given key. The write
+// function receives the path where the archive should be created.
+func storeArchive(key, filename string, write func(string) error) (*runtimeArchive, error) {
+	dir := filepath.Join(ARCHIVE_CACHE, key)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// Write to a partial directory first so readers never see an incomplete archive
+	partial, err := os.MkdirTemp(dir, ".partial")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(partial)
+
+	if err := write(filepath.Join(partial, filename)); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, filename)
+	if err := os.Rename(filepath.Join(partial, filename), path); err != nil {
+		return nil, err
+	}
+
+	// Make room for the new archive
+	if err := evictArchives(path); err != nil {
+		log.Println(err)
+	}
+
+	return &runtimeArchive{Path: path, Filename: filename}, nil
+}
+
+// CachedArchive is a finished archive in the archive cache.
+type cachedArchive struct {
+	path     string
+	size     int64
+	accessed time.Time
+}
+
+// ListArchives returns the finished archives in the archive cache.
+func listArchives() ([]cachedArchive, error) {
+	keys, err := os.ReadDir(ARCHIVE_CACHE)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []cachedArchive
+	for _, key := range keys {
+		if !key.IsDir() {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(ARCHIVE_CACHE, key.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			archives = append(archives, cachedArchive{filepath.Join(ARCHIVE_CACHE, key.Name(), entry.Name()), info.Size(), info.ModTime()})
+		}
+	}
+
+	return archives, nil
+}
+
+// Only one archive eviction runs at a time
+var archiveEvictionLock sync.Mutex
+
+// EvictArchives removes the least recently used archives until the archive
+// cache is within ARCHIVE_CACHE_MAX_SIZE. The archive at the given path is
+// kept since it was just generated.
+func evictArchives(keep string) error {
+	if ARCHIVE_CACHE_MAX_SIZE == 0 {
+		return nil
+	}
+
+	archiveEvictionLock.Lock()
+	defer archiveEvictionLock.Unlock()
+
+	archives, err := listArchives()
+	if err != nil {
+		return err
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].accessed.Before(archives[j].accessed)
+	})
+
+	var size int64
+	for _, archive := range archives {
+		size += archive.size
+	}
+
+	for _, archive := range archives {
+		if size <= ARCHIVE_CACHE_MAX_SIZE {
+			break
+		}
+		if archive.path == keep {
+			continue
+		}
+
+		log.Println("Evicting archive:", archive.path)
+		if err := os.Remove(archive.path); err != nil {
+			return err
+		}
+
+		// The key directory is only removed once it's empty
+		_ = os.Remove(filepath.Dir(archive.path))
+
+		size -= archive.size
+	}
+
+	return nil
+}
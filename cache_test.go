@@ -0,0 +1,135 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeModules(t *testing.T) {
+	assert.Equal(t, []string{"java.base"}, normalizeModules(nil))
+	assert.Equal(t, []string{"java.base", "java.desktop", "jdk.zipfs"}, normalizeModules([]string{"jdk.zipfs", "java.desktop", "jdk.zipfs"}))
+}
+
+func TestArchiveKey(t *testing.T) {
+	artifacts, dir := newTemporaryDirectory("artifacts")
+	defer os.RemoveAll(dir)
+
+	req := runtimeRequest{Modules: []string{"java.desktop", "java.base"}, Endian: "little", Implementation: "hotspot", Options: defaultJlinkOptions()}
+	key, err := archiveKey("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", &req, artifacts)
+	assert.NoError(t, err)
+
+	// The order of modules is irrelevant
+	req.Modules = []string{"java.desktop"}
+	same, err := archiveKey("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", &req, artifacts)
+	assert.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	// Options are relevant
+	req.Options.StripDebug = false
+	different, err := archiveKey("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", &req, artifacts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, different)
+
+	// Artifacts are relevant
+	req.Options = defaultJlinkOptions()
+	assert.NoError(t, os.WriteFile(filepath.Join(artifacts, "slf4j-api-2.0.0.jar"), []byte("jar"), os.ModePerm))
+	different, err = archiveKey("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", &req, artifacts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, different)
+}
+
+func TestArchiveCache(t *testing.T) {
+	defer func(cache string) { ARCHIVE_CACHE = cache }(ARCHIVE_CACHE)
+	cache, dir := newTemporaryDirectory("archive_cache")
+	defer os.RemoveAll(dir)
+	ARCHIVE_CACHE = cache
+
+	_, exists := lookupArchive("abc", "jdk.tar.gz")
+	assert.False(t, exists)
+
+	// Failed archives are not cached
+	_, err := storeArchive("abc", "jdk.tar.gz", func(path string) error {
+		return os.WriteFile(path+"/missing", []byte("archive"), os.ModePerm)
+	})
+	assert.Error(t, err)
+	_, exists = lookupArchive("abc", "jdk.tar.gz")
+	assert.False(t, exists)
+
+	archive, err := storeArchive("abc", "jdk.tar.gz", func(path string) error {
+		return os.WriteFile(path, []byte("archive"), os.ModePerm)
+	})
+	assert.NoError(t, err)
+	assert.False(t, archive.Cached)
+	assert.Equal(t, "MISS", archive.cacheStatus())
+
+	// No partial files are left behind
+	entries, err := os.ReadDir(filepath.Join(cache, "abc"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	archive, exists = lookupArchive("abc", "jdk.tar.gz")
+	assert.True(t, exists)
+	assert.Equal(t, "HIT", archive.cacheStatus())
+
+	contents, err := os.ReadFile(archive.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", string(contents))
+}
+
+func TestEvictArchives(t *testing.T) {
+	defer func(cache string, maxSize int64) { ARCHIVE_CACHE, ARCHIVE_CACHE_MAX_SIZE = cache, maxSize }(ARCHIVE_CACHE, ARCHIVE_CACHE_MAX_SIZE)
+	ARCHIVE_CACHE = t.TempDir()
+	ARCHIVE_CACHE_MAX_SIZE = 0
+
+	// Three archives of 100 bytes, accessed in order
+	now := time.Now()
+	var paths []string
+	for i, key := range []string{"a", "b", "c"} {
+		archive, err := storeArchive(key, "jdk.tar.gz", func(path string) error {
+			return os.WriteFile(path, make([]byte, 100), os.ModePerm)
+		})
+		assert.NoError(t, err)
+		accessed := now.Add(time.Duration(i-10) * time.Minute)
+		assert.NoError(t, os.Chtimes(archive.Path, accessed, accessed))
+		paths = append(paths, archive.Path)
+	}
+
+	// Serving an archive makes it the most recently used
+	_, exists := lookupArchive("a", "jdk.tar.gz")
+	assert.True(t, exists)
+
+	// A new archive evicts the least recently used ones
+	ARCHIVE_CACHE_MAX_SIZE = 250
+	archive, err := storeArchive("d", "jdk.tar.gz", func(path string) error {
+		return os.WriteFile(path, make([]byte, 100), os.ModePerm)
+	})
+	assert.NoError(t, err)
+	assert.FileExists(t, paths[0])
+	assert.NoDirExists(t, filepath.Dir(paths[1]))
+	assert.NoDirExists(t, filepath.Dir(paths[2]))
+	assert.FileExists(t, archive.Path)
+
+	// The new archive is kept even if it's too large by itself
+	ARCHIVE_CACHE_MAX_SIZE = 50
+	archive, err = storeArchive("e", "jdk.tar.gz", func(path string) error {
+		return os.WriteFile(path, make([]byte, 100), os.ModePerm)
+	})
+	assert.NoError(t, err)
+	assert.NoFileExists(t, paths[0])
+	assert.FileExists(t, archive.Path)
+}
@@ -0,0 +1,113 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The supported output archive formats and their media types
+var archiveFormats = map[string]string{
+	"tar.gz":  "application/gzip",
+	"zip":     "application/zip",
+	"tar.xz":  "application/x-xz",
+	"tar.zst": "application/zstd",
+}
+
+// Media types which may be used to request an archive format in the Accept header
+var acceptedFormats = map[string]string{
+	"application/gzip":   "tar.gz",
+	"application/x-gzip": "tar.gz",
+	"application/zip":    "zip",
+	"application/x-zip":  "zip",
+	"application/x-xz":   "tar.xz",
+	"application/zstd":   "tar.zst",
+}
+
+// ArchiveFormat returns the format of an archive according to its filename.
+func archiveFormat(filename string) string {
+	for format := range archiveFormats {
+		if strings.HasSuffix(filename, "."+format) {
+			return format
+		}
+	}
+
+	return ""
+}
+
+// DefaultArchiveFormat returns the usual archive format for a platform.
+func defaultArchiveFormat(platform string) string {
+	if platform == "windows" {
+		return "zip"
+	}
+
+	return "tar.gz"
+}
+
+// ArchiveFilename replaces the extension of an upstream package name with the
+// given archive format.
+func archiveFilename(packageName, format string) string {
+	if current := archiveFormat(packageName); current != "" {
+		packageName = strings.TrimSuffix(packageName, "."+current)
+	}
+
+	return packageName + "." + format
+}
+
+// ArchiveContentType returns the media type for an archive according to its filename.
+func archiveContentType(filename string) string {
+	if contentType, exists := archiveFormats[archiveFormat(filename)]; exists {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// RequestFormat returns the explicitly requested archive format or else the
+// supported format with the highest quality value in the Accept header (the
+// first one if several are equally preferred). Media types with q=0 are never
+// chosen. An empty string means the format of the upstream package should be
+// used.
+func requestFormat(context *gin.Context, format string) string {
+	if format != "" {
+		return format
+	}
+
+	best, bestQuality := "", 0.0
+	for _, mediaRange := range strings.Split(context.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		accepted, exists := acceptedFormats[mediaType]
+		if !exists {
+			continue
+		}
+
+		quality := 1.0
+		if q, exists := params["q"]; exists {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = accepted, quality
+		}
+	}
+
+	return best
+}
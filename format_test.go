@@ -0,0 +1,56 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveFilename(t *testing.T) {
+	assert.Equal(t, "OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", archiveFilename("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", "tar.gz"))
+	assert.Equal(t, "OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.zst", archiveFilename("OpenJDK11U-jdk_x64_linux_hotspot_11.0.8_10.tar.gz", "tar.zst"))
+	assert.Equal(t, "OpenJDK11U-jdk_x64_windows_hotspot_11.0.8_10.tar.xz", archiveFilename("OpenJDK11U-jdk_x64_windows_hotspot_11.0.8_10.zip", "tar.xz"))
+
+	assert.Equal(t, "jdk-17.0.1+12.zip", archiveFilename("jdk-17.0.1+12", "zip"))
+
+	assert.Equal(t, "zip", archiveFormat("OpenJDK11U-jdk_x64_windows_hotspot_11.0.8_10.zip"))
+	assert.Equal(t, "", archiveFormat("jdk-17.0.1+12"))
+	assert.Equal(t, "zip", defaultArchiveFormat("windows"))
+	assert.Equal(t, "tar.gz", defaultArchiveFormat("mac"))
+	assert.Equal(t, "application/zstd", archiveContentType("jdk.tar.zst"))
+	assert.Equal(t, "application/octet-stream", archiveContentType("jdk.pkg"))
+}
+
+func TestRequestFormat(t *testing.T) {
+	newContext := func(accept string) *gin.Context {
+		context, _ := gin.CreateTestContext(httptest.NewRecorder())
+		context.Request = httptest.NewRequest("GET", "/runtime/x64/linux/11.0.8+10", nil)
+		context.Request.Header.Set("Accept", accept)
+		return context
+	}
+
+	assert.Equal(t, "", requestFormat(newContext(""), ""))
+	assert.Equal(t, "", requestFormat(newContext("*/*"), ""))
+	assert.Equal(t, "zip", requestFormat(newContext("application/x-xz"), "zip"))
+	assert.Equal(t, "tar.xz", requestFormat(newContext("text/html, application/x-xz;q=0.9, */*;q=0.8"), ""))
+	assert.Equal(t, "tar.zst", requestFormat(newContext("application/zstd"), ""))
+
+	// Quality values are respected and q=0 excludes a media type
+	assert.Equal(t, "tar.gz", requestFormat(newContext("application/zip;q=0, application/gzip"), ""))
+	assert.Equal(t, "", requestFormat(newContext("application/zip;q=0"), ""))
+	assert.Equal(t, "tar.xz", requestFormat(newContext("application/zip;q=0.5, application/x-xz;q=0.8"), ""))
+	assert.Equal(t, "zip", requestFormat(newContext("application/zip, application/x-xz"), ""))
+}
@@ -0,0 +1,97 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// A dependency in the output of "gradle dependencies", like:
+	// +--- org.slf4j:slf4j-api:1.7.30 -> 1.7.32 (*)
+	// where (*) marks a repeated dependency, (c) a constraint and (n) a
+	// dependency that couldn't be resolved
+	gradleTreeDependency = regexp.MustCompile(`^[|\s]*[+\\]--- ([\w\.-]+):([\w\.-]+)(?::([\w\.-]+))?(?: -> ([\w\.-]+))?(?: \((\w+|\*)\))?\s*$`)
+
+	// A marker for the output of "gradle dependencies"
+	gradleTreeCheck = regexp.MustCompile(`(?m)^[|\s]*[+\\]--- `)
+)
+
+// ParseGradleDependencies extracts G:A:V coordinates from a gradle.lockfile or
+// from the output of "gradle dependencies --configuration runtimeClasspath".
+// Each artifact appears once because Gradle has already resolved the versions.
+func parseGradleDependencies(file string) []string {
+	if gradleTreeCheck.MatchString(file) {
+		return parseGradleTree(file)
+	}
+
+	return parseGradleLockfile(file)
+}
+
+// ParseGradleLockfile extracts the runtimeClasspath coordinates from a gradle.lockfile.
+func parseGradleLockfile(file string) []string {
+	var artifacts []string
+	for _, line := range strings.Split(file, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Since Gradle 7, each line ends with the configurations containing the dependency
+		gav, configurations, found := strings.Cut(line, "=")
+		if found && !strings.Contains(","+configurations+",", ",runtimeClasspath,") {
+			continue
+		}
+
+		if artifactCheck.MatchString(gav) {
+			artifacts = append(artifacts, gav)
+		}
+	}
+
+	return artifacts
+}
+
+// ParseGradleTree extracts the resolved coordinates from the output of "gradle dependencies".
+func parseGradleTree(file string) []string {
+	seen := make(map[string]bool)
+
+	var artifacts []string
+	for _, line := range strings.Split(file, "\n") {
+		match := gradleTreeDependency.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		// Skip constraints and dependencies that weren't resolved
+		if match[5] == "c" || match[5] == "n" {
+			continue
+		}
+
+		// Use the version selected by conflict resolution
+		version := match[3]
+		if match[4] != "" {
+			version = match[4]
+		}
+		if version == "" {
+			continue
+		}
+
+		ga := match[1] + ":" + match[2]
+		if !seen[ga] {
+			seen[ga] = true
+			artifacts = append(artifacts, ga+":"+version)
+		}
+	}
+
+	return artifacts
+}
@@ -0,0 +1,65 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGradleLockfile(t *testing.T) {
+	assert.Equal(t, []string{"com.fasterxml.jackson.core:jackson-core:2.12.0", "org.slf4j:slf4j-api:1.7.32"}, parseGradleDependencies(`
+# This is a Gradle generated file for dependency locking.
+# Manual edits can break the build and are not advised.
+# This file is expected to be part of source control.
+com.fasterxml.jackson.core:jackson-core:2.12.0=compileClasspath,runtimeClasspath
+org.junit.jupiter:junit-jupiter-api:5.7.0=testCompileClasspath,testRuntimeClasspath
+org.slf4j:slf4j-api:1.7.32=runtimeClasspath
+empty=annotationProcessor
+`))
+
+	// Lockfiles from before Gradle 7 have one file per configuration
+	assert.Equal(t, []string{"org.slf4j:slf4j-api:1.7.32"}, parseGradleDependencies(`
+# This is a Gradle generated file for dependency locking.
+org.slf4j:slf4j-api:1.7.32
+`))
+}
+
+func TestParseGradleTree(t *testing.T) {
+	assert.Equal(t, []string{
+		"org.slf4j:slf4j-api:1.7.32",
+		"com.google.guava:guava:30.1-jre",
+		"com.google.guava:failureaccess:1.0.1",
+		"org.checkerframework:checker-qual:3.8.0",
+		"com.fasterxml.jackson.core:jackson-databind:2.12.1",
+	}, parseGradleDependencies(`
+runtimeClasspath - Runtime classpath of source set 'main'.
++--- org.slf4j:slf4j-api:1.7.30 -> 1.7.32
++--- project :core
+|    \--- org.slf4j:slf4j-api:1.7.32 (*)
++--- com.google.guava:guava:30.1-jre
+|    +--- com.google.guava:failureaccess:1.0.1
+|    \--- org.checkerframework:checker-qual:3.8.0
++--- com.fasterxml.jackson.core:jackson-databind -> 2.12.1
++--- com.fasterxml.jackson:jackson-bom:2.12.1 (c)
+\--- org.example:missing:1.0 FAILED
+
+(c) - dependency constraint
+(*) - dependencies omitted (listed previously)
+`))
+
+	// Repeated dependencies are included too
+	assert.Equal(t, []string{"org.slf4j:slf4j-api:1.7.32"}, parseGradleDependencies(`
+\--- org.slf4j:slf4j-api:1.7.30 -> 1.7.32 (*)
+`))
+}
@@ -0,0 +1,208 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveUploadedJars stores the JAR files of a multipart request in a temporary
+// directory and returns their paths along with the directory.
+func saveUploadedJars(context *gin.Context) ([]string, string, error) {
+	form, err := context.MultipartForm()
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := form.File["jars"]
+	if len(files) == 0 {
+		return nil, "", errors.New("No JAR files found in the 'jars' field")
+	}
+
+	jars, dir := newTemporaryDirectory("jars")
+
+	var paths []string
+	for i, file := range files {
+		if !strings.HasSuffix(file.Filename, ".jar") {
+			os.RemoveAll(dir)
+			return nil, "", errors.New("Not a JAR file: " + file.Filename)
+		}
+
+		// Prefix an index in case multiple JARs have the same name
+		path := filepath.Join(jars, fmt.Sprintf("%d-%s", i, filepath.Base(file.Filename)))
+		if err := context.SaveUploadedFile(file, path); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, dir, nil
+}
+
+// DetectModules downloads a local runtime for the given vendor, implementation,
+// version and release type and uses its jdeps tool to find the JDK modules
+// required by the JARs.
+func detectModules(vendor, implementation, version, releaseType string, jars []string) ([]string, error) {
+
+	// The major version of latest and lts is only known once they're resolved
+	if version != "latest" && version != "lts" {
+		majorVersion, err := getMajorVersion(version)
+		if err != nil {
+			return nil, &buildError{"Invalid Java version", err}
+		}
+		if majorVersion < 11 {
+			return nil, &buildError{"Module detection requires Java 11 or later", nil}
+		}
+	}
+
+	// Lookup a runtime containing a compatible version of jdeps for local use
+	if vendor == "" {
+		vendor = DEFAULT_VENDOR
+	}
+	if releaseType == "" {
+		releaseType = releaseTypeOf(version)
+	}
+	local, err := lookupJdk(vendor, LOCAL_ARCH, LOCAL_PLATFORM, implementation, version, releaseType)
+	if err != nil {
+		return nil, newBuildError("Failed to find local runtime", err)
+	}
+
+	majorVersion, err := getMajorVersion(local.Version)
+	if err != nil {
+		return nil, &buildError{"Invalid Java version", err}
+	}
+	if majorVersion < 11 {
+		return nil, &buildError{"Module detection requires Java 11 or later", nil}
+	}
+
+	// Download the local runtime
+	localRuntimePath, releaseLocal, err := downloadJdk(local)
+	if err != nil {
+		log.Println(err)
+		return nil, newBuildError("Failed to download local runtime", err)
+	}
+	defer releaseLocal()
+
+	modules, err := jdeps(localRuntimePath, majorVersion, jars)
+	if err != nil {
+		log.Println(err)
+		return nil, &buildError{"Failed to detect required modules", err}
+	}
+
+	return modules, nil
+}
+
+// Jdeps uses a standard JDK runtime to list the JDK modules required by the
+// given JARs.
+func jdeps(jdk string, majorVersion int, jars []string) ([]string, error) {
+
+	jdeps, err := localTool(jdk, "jdeps")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(jdeps, append([]string{
+		// Print a comma-separated list of module dependencies
+		"--print-module-deps",
+		// Don't fail on dependencies which weren't uploaded
+		"--ignore-missing-deps",
+		// Analyze multi-release JARs for the target version
+		"--multi-release", strconv.Itoa(majorVersion),
+	}, jars...)...)
+
+	log.Println("JDEPS:", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModuleDeps(string(out)), nil
+}
+
+// ParseModuleDeps extracts the module list from the output of jdeps --print-module-deps.
+func parseModuleDeps(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	var modules []string
+	for _, module := range strings.Split(lines[len(lines)-1], ",") {
+		if module = strings.TrimSpace(module); moduleCheck.MatchString(module) {
+			modules = append(modules, module)
+		}
+	}
+
+	return modules
+}
+
+// DescribeModules uses a standard JDK runtime to read the module names of the
+// modular JARs in the given directory. Automatic modules are skipped because
+// they can't be linked into a runtime image.
+func describeModules(jdk, dir string) ([]string, error) {
+
+	jar, err := localTool(jdk, "jar")
+	if err != nil {
+		return nil, err
+	}
+
+	jars, err := filepath.Glob(filepath.Join(dir, "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	for _, path := range jars {
+		out, err := exec.Command(jar, "--describe-module", "--file", path).Output()
+		if err != nil {
+			return nil, err
+		}
+
+		if module := parseModuleDescriptor(string(out)); module != "" {
+			modules = append(modules, module)
+		}
+	}
+
+	return modules, nil
+}
+
+// ParseModuleDescriptor extracts the module name from the output of
+// jar --describe-module. An empty string is returned for automatic modules.
+func parseModuleDescriptor(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		// Multi-release JARs list their releases first
+		if line == "" || strings.HasPrefix(line, "releases:") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "No module descriptor found") {
+			return ""
+		}
+
+		name := strings.Fields(line)[0]
+		if i := strings.Index(name, "@"); i != -1 {
+			name = name[:i]
+		}
+		return name
+	}
+
+	return ""
+}
@@ -0,0 +1,79 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModuleDeps(t *testing.T) {
+	assert.Equal(t, []string{"java.base", "java.logging", "java.sql"}, parseModuleDeps("java.base,java.logging,java.sql\n"))
+	assert.Equal(t, []string{"java.base"}, parseModuleDeps("Warning: split package: javax.annotation\njava.base\n"))
+	assert.Nil(t, parseModuleDeps(""))
+}
+
+func TestParseModuleDescriptor(t *testing.T) {
+	assert.Equal(t, "org.slf4j", parseModuleDescriptor(`org.slf4j@2.0.0-alpha1 jar:file:///tmp/slf4j-api-2.0.0-alpha1.jar!/module-info.class
+exports org.slf4j
+requires java.base mandated
+`))
+
+	assert.Equal(t, "com.fasterxml.jackson.core", parseModuleDescriptor(`releases: 9
+
+com.fasterxml.jackson.core jar:file:///tmp/jackson-core-2.12.0.jar!/META-INF/versions/9/module-info.class
+exports com.fasterxml.jackson.core
+`))
+
+	assert.Equal(t, "", parseModuleDescriptor(`No module descriptor found. Derived automatic module.
+
+commons.lang@2.6 automatic
+requires java.base mandated
+`))
+}
+
+func TestSaveUploadedJars(t *testing.T) {
+	newContext := func(files map[string]string) *gin.Context {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for name, contents := range files {
+			part, _ := writer.CreateFormFile("jars", name)
+			part.Write([]byte(contents))
+		}
+		writer.Close()
+
+		context, _ := gin.CreateTestContext(httptest.NewRecorder())
+		context.Request = httptest.NewRequest("POST", "/modules/11.0.8+10", &body)
+		context.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		return context
+	}
+
+	jars, dir, err := saveUploadedJars(newContext(map[string]string{"app.jar": "jar"}))
+	assert.NoError(t, err)
+	assert.Len(t, jars, 1)
+	contents, err := os.ReadFile(jars[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "jar", string(contents))
+	os.RemoveAll(dir)
+
+	_, _, err = saveUploadedJars(newContext(map[string]string{"app.exe": "exe"}))
+	assert.Error(t, err)
+
+	_, _, err = saveUploadedJars(newContext(map[string]string{}))
+	assert.Error(t, err)
+}
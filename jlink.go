@@ -12,13 +12,15 @@
 package main
 
 import (
-	"bytes"
+	"crypto/subtle"
+	"errors"
 	"html/template"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -38,11 +40,29 @@ var (
 	// A cache directory for base runtimes
 	RT_CACHE = filepath.FromSlash(os.TempDir() + "/runtime_cache")
 
+	// The maximum size of RT_CACHE in bytes (unlimited if zero)
+	RT_CACHE_MAX_SIZE int64 = 0
+
+	// The minimum free space on the RT_CACHE filesystem in bytes (unchecked if zero)
+	RT_CACHE_MIN_FREE int64 = 0
+
+	// A cache directory for generated runtime archives (next to RT_CACHE by default)
+	ARCHIVE_CACHE = ""
+
+	// The maximum size of ARCHIVE_CACHE in bytes (unlimited if zero)
+	ARCHIVE_CACHE_MAX_SIZE int64 = 5 << 30
+
 	// A directory for short-lived files
 	TMP = os.TempDir()
 
-	// Whether Maven Central integration is enabled
-	MAVEN_CENTRAL = false
+	// Whether Maven integration is enabled
+	MAVEN_INTEGRATION = false
+
+	// The Maven repositories to search in order
+	MAVEN_REPOSITORIES = []mavenRepository{mavenCentralRepository}
+
+	// Whether Maven files without a checksum are rejected
+	MAVEN_REQUIRE_CHECKSUMS = false
 
 	// The platform for local runtimes
 	LOCAL_PLATFORM = determineLocalPlatform()
@@ -52,22 +72,45 @@ var (
 
 	// The default path for swagger documentation
 	SWAGGER_PATH = "/app/swagger-ui"
+
+	// The number of workers for asynchronous builds
+	BUILD_WORKERS = 2
+
+	// How long finished asynchronous builds are kept
+	BUILD_RETENTION = time.Hour
+
+	// Whether only local content can be used
+	OFFLINE = false
+
+	// A JSON file listing the releases available in offline mode
+	OFFLINE_INDEX = ""
+
+	// The JDK vendor when requests don't specify one
+	DEFAULT_VENDOR = "adoptopenjdk"
+
+	// A directory of pre-provisioned JDKs for the local vendor (disabled if empty)
+	LOCAL_JDKS = ""
+
+	// A bearer token for administrative endpoints (disabled if empty)
+	ADMIN_TOKEN = ""
 )
 
 // A client for downloading artifacts and release metadata from api.adoptopenjdk.net
 var adoptium = &http.Client{
-	Timeout: time.Second * 120,
+	Timeout:   time.Second * 120,
+	Transport: newTransport(),
 }
 
-// A client for downloading Maven Central artifacts
-var mavenCentral = &http.Client{
-	Timeout: time.Second * 60,
+// A client for downloading Maven artifacts
+var mavenClient = &http.Client{
+	Timeout:   time.Second * 60,
+	Transport: newTransport(),
 }
 
 // RuntimeRequest represents an incoming request from the JSON endpoint.
 type runtimeRequest struct {
 
-	// Maven Central artifacts in G:A:V format
+	// Maven artifacts in G:A:V format
 	Artifacts []string `json:"artifacts"`
 
 	// The modules to include in the runtime
@@ -87,23 +130,64 @@ type runtimeRequest struct {
 
 	// The implementation type
 	Implementation string `json:"implementation"`
-}
 
-func main() {
+	// The JDK vendor
+	Vendor string `json:"vendor"`
 
-	router := gin.Default()
+	// The release type (ga or ea)
+	ReleaseType string `json:"release_type"`
 
-	router.LoadHTMLGlob("templates/*.tmpl.html")
+	// The jlink plugin options
+	Options jlinkOptions `json:"options"`
+
+	// The output archive format
+	Format string `json:"format"`
+
+	// Launchers to generate in name=module/mainclass format
+	Launchers []string `json:"launchers"`
+
+	// Whether the modules of the resolved artifacts are added to the runtime
+	ArtifactModules bool `json:"artifact_modules"`
+
+	// Whether the artifacts are already fully resolved (their dependencies aren't downloaded)
+	Resolved bool `json:"resolved"`
+
+	// The exclusions declared on artifacts (keyed by G:A) in an uploaded pom.xml
+	Exclusions map[string][]mavenExclusion `json:"-"`
+
+	// The dependency management (keyed by G:A) of an uploaded pom.xml, which also
+	// applies to transitive dependencies
+	Managed map[string]mavenDependency `json:"-"`
+}
+
+func main() {
 
 	// Override environment variables
 	if port, exists := os.LookupEnv("PORT"); exists {
 		PORT = port
 	}
-	if maven_central, exists := os.LookupEnv("MAVEN_CENTRAL"); exists {
-		if b, err := strconv.ParseBool(maven_central); err == nil {
-			MAVEN_CENTRAL = b
+	// MAVEN_CENTRAL is the old name of MAVEN_INTEGRATION
+	for _, name := range []string{"MAVEN_CENTRAL", "MAVEN_INTEGRATION"} {
+		if maven, exists := os.LookupEnv(name); exists {
+			if b, err := strconv.ParseBool(maven); err == nil {
+				MAVEN_INTEGRATION = b
+			} else {
+				log.Fatal("Invalid value for " + name + " flag")
+			}
+		}
+	}
+	if repositories, exists := os.LookupEnv("MAVEN_REPOSITORIES"); exists {
+		if r, err := parseMavenRepositories(repositories); err == nil {
+			MAVEN_REPOSITORIES = r
+		} else {
+			log.Fatal(err)
+		}
+	}
+	if require, exists := os.LookupEnv("MAVEN_REQUIRE_CHECKSUMS"); exists {
+		if b, err := strconv.ParseBool(require); err == nil {
+			MAVEN_REQUIRE_CHECKSUMS = b
 		} else {
-			log.Fatal("Invalid value for MAVEN_CENTRAL flag")
+			log.Fatal("Invalid value for MAVEN_REQUIRE_CHECKSUMS flag")
 		}
 	}
 	if arch, exists := os.LookupEnv("LOCAL_ARCH"); exists {
@@ -112,12 +196,126 @@ func main() {
 	if cache, exists := os.LookupEnv("RT_CACHE"); exists {
 		RT_CACHE = cache
 	}
+	if size, exists := os.LookupEnv("RT_CACHE_MAX_SIZE"); exists {
+		if b, err := parseByteSize(size); err == nil {
+			RT_CACHE_MAX_SIZE = b
+		} else {
+			log.Fatal("Invalid value for RT_CACHE_MAX_SIZE flag")
+		}
+	}
+	if free, exists := os.LookupEnv("RT_CACHE_MIN_FREE"); exists {
+		if b, err := parseByteSize(free); err == nil {
+			RT_CACHE_MIN_FREE = b
+		} else {
+			log.Fatal("Invalid value for RT_CACHE_MIN_FREE flag")
+		}
+	}
+	if cache, exists := os.LookupEnv("ARCHIVE_CACHE"); exists {
+		ARCHIVE_CACHE = cache
+	} else {
+		ARCHIVE_CACHE = filepath.Join(filepath.Dir(RT_CACHE), "archive_cache")
+	}
+	if size, exists := os.LookupEnv("ARCHIVE_CACHE_MAX_SIZE"); exists {
+		if b, err := parseByteSize(size); err == nil {
+			ARCHIVE_CACHE_MAX_SIZE = b
+		} else {
+			log.Fatal("Invalid value for ARCHIVE_CACHE_MAX_SIZE flag")
+		}
+	}
 	if tmp, exists := os.LookupEnv("TMP"); exists {
 		TMP = tmp
 	}
+	if workers, exists := os.LookupEnv("BUILD_WORKERS"); exists {
+		if i, err := strconv.Atoi(workers); err == nil && i > 0 {
+			BUILD_WORKERS = i
+		} else {
+			log.Fatal("Invalid value for BUILD_WORKERS flag")
+		}
+	}
+	if retention, exists := os.LookupEnv("BUILD_RETENTION"); exists {
+		if d, err := time.ParseDuration(retention); err == nil {
+			BUILD_RETENTION = d
+		} else {
+			log.Fatal("Invalid value for BUILD_RETENTION flag")
+		}
+	}
+	if offline, exists := os.LookupEnv("OFFLINE"); exists {
+		if b, err := strconv.ParseBool(offline); err == nil {
+			OFFLINE = b
+		} else {
+			log.Fatal("Invalid value for OFFLINE flag")
+		}
+	}
+	if index, exists := os.LookupEnv("OFFLINE_INDEX"); exists {
+		OFFLINE_INDEX = index
+	}
+	if token, exists := os.LookupEnv("ADMIN_TOKEN"); exists {
+		ADMIN_TOKEN = token
+	}
+	if jdks, exists := os.LookupEnv("LOCAL_JDKS"); exists {
+		LOCAL_JDKS = jdks
+		jdkProviders[localVendor] = newLocalProvider(jdks)
+	}
+	if vendor, exists := os.LookupEnv("DEFAULT_VENDOR"); exists {
+		if _, valid := jdkProviders[vendor]; valid {
+			DEFAULT_VENDOR = vendor
+		} else {
+			log.Fatal(invalidVendor())
+		}
+	}
 	_ = os.MkdirAll(RT_CACHE, os.ModePerm)
+	_ = os.MkdirAll(ARCHIVE_CACHE, os.ModePerm)
 	_ = os.MkdirAll(TMP, os.ModePerm)
 
+	metadataTTL := defaultMetadataTTL
+	if ttl, exists := os.LookupEnv("METADATA_TTL"); exists {
+		if t, err := parseMetadataTTL(ttl); err == nil {
+			metadataTTL = t
+		} else {
+			log.Fatal(err)
+		}
+	}
+	metadataCache = newMetadataStore(filepath.Join(RT_CACHE, "metadata.json"), metadataTTL)
+
+	warmTargets, err := parseWarmTargets(strings.Split(os.Getenv("WARM_RUNTIMES"), ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The warm subcommand downloads runtimes into RT_CACHE and exits
+	if len(os.Args) > 1 && os.Args[1] == "warm" {
+		if len(os.Args) > 2 {
+			if warmTargets, err = parseWarmTargets(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := warmRuntimes(warmTargets); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := cleanRuntimeCache(); err != nil {
+		log.Println(err)
+	}
+	if err := evictRuntimes(); err != nil {
+		log.Println(err)
+	}
+	if err := evictArchives(""); err != nil {
+		log.Println(err)
+	}
+
+	// Download the configured runtimes in the background
+	if len(warmTargets) > 0 {
+		go warmRuntimes(warmTargets)
+	}
+
+	startBuildWorkers(BUILD_WORKERS)
+
+	router := gin.Default()
+
+	router.LoadHTMLGlob("templates/*.tmpl.html")
+
 	router.GET("/", func(context *gin.Context) {
 		readmeFile, err := ioutil.ReadFile("./README.md")
 		if err != nil {
@@ -134,61 +332,66 @@ func main() {
 	// An endpoint for API documentation
 	router.Static("/swagger-ui", SWAGGER_PATH)
 
+	// An endpoint for invalidating cached release metadata
+	router.DELETE("/metadata", func(context *gin.Context) {
+		if !authorizeAdmin(context) {
+			return
+		}
+
+		entryType := context.Query("type")
+		if _, exists := defaultMetadataTTL[entryType]; entryType != "" && !exists {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Invalid metadata type"})
+			return
+		}
+
+		removed, err := metadataCache.invalidate(entryType)
+		if err != nil {
+			log.Println(err)
+		}
+		context.JSON(http.StatusOK, gin.H{"success": true, "removed": removed})
+	})
+
 	// An endpoint for health checks
 	router.GET("/status", func(context *gin.Context) {
 
-		if LOCAL_PLATFORM != "windows" {
-			out, err := exec.Command("df", "-B1", "--output=avail", RT_CACHE).Output()
-			if err == nil {
-				free, _ := strconv.Atoi(strings.Fields(string(out))[1])
-				context.JSON(http.StatusOK, gin.H{"success": true, "cache_free": free})
-				return
-			}
+		status := gin.H{"success": true}
+		if free, err := cacheFree(); err == nil {
+			status["cache_free"] = free
+		}
+		if len(warmTargets) > 0 {
+			status["warm"] = warmStatus()
 		}
 
-		context.JSON(http.StatusOK, gin.H{"success": true})
+		context.JSON(http.StatusOK, status)
 	})
 
 	// An endpoint for runtime requests
 	router.GET("/runtime/:arch/:os/:version", func(context *gin.Context) {
-
-		var (
-			arch     = context.Param("arch")
-			endian   = context.Query("endian")
-			impl     = context.DefaultQuery("implementation", "hotspot")
-			platform = context.Param("os")
-			version  = context.Param("version")
-			modules  = strings.Split(context.DefaultQuery("modules", "java.base"), ",")
-		)
-
-		var artifacts []string
-		if a := context.Query("artifacts"); a != "" {
-			if MAVEN_CENTRAL {
-				artifacts = strings.Split(a, ",")
-			} else {
-				context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven Central integration is disabled"})
-				return
-			}
+		req, err := parseRuntimeQuery(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
 		}
 
-		handleRequest(context, platform, arch, version, endian, impl, modules, artifacts)
+		handleRequest(context, req)
 	})
 
 	// An endpoint for runtime requests (JSON)
 	router.POST("/runtime", func(context *gin.Context) {
-		var req runtimeRequest
+		req := runtimeRequest{Options: defaultJlinkOptions()}
 
 		err := context.BindJSON(&req)
 		if err != nil {
 			return
 		}
 
-		if !MAVEN_CENTRAL && len(req.Artifacts) > 0 {
-			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven Central integration is disabled"})
+		if !MAVEN_INTEGRATION && len(req.Artifacts) > 0 {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven integration is disabled"})
 			return
 		}
 
-		handleRequest(context, req.Platform, req.Arch, req.Version, req.Endian, req.Implementation, req.Modules, req.Artifacts)
+		req.Format = requestFormat(context, req.Format)
+		handleRequest(context, req)
 	})
 
 	// An endpoint for runtime requests containing a module-info.java file
@@ -199,25 +402,212 @@ func main() {
 			return
 		}
 
+		req, err := parseRuntimeQuery(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		req.Modules = parseModuleInfo(string(bytes))
+		handleRequest(context, req)
+	})
+
+	// An endpoint for runtime requests containing a pom.xml file
+	router.POST("/runtime/:arch/:os/:version/pom", func(context *gin.Context) {
+		if !MAVEN_INTEGRATION {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven integration is disabled"})
+			return
+		}
+
+		bytes, err := context.GetRawData()
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "The request body must be a valid pom.xml file"})
+			return
+		}
+
+		pom, err := parsePom(bytes)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "The request body must be a valid pom.xml file"})
+			return
+		}
+
+		if err := resolvePom(pom, 0); err != nil {
+			reason := newBuildError("Failed to resolve the parents and imports of pom.xml", err).Reason
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": reason})
+			log.Println(err)
+			return
+		}
+
+		req, err := parseRuntimeQuery(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		req.Artifacts = append(req.Artifacts, pom.artifacts()...)
+		req.Exclusions = pom.exclusions()
+		req.Managed = pom.managed()
+		req.ArtifactModules = true
+		handleRequest(context, req)
+	})
+
+	// An endpoint for runtime requests containing a gradle.lockfile or the output of "gradle dependencies"
+	router.POST("/runtime/:arch/:os/:version/gradle", func(context *gin.Context) {
+		if !MAVEN_INTEGRATION {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven integration is disabled"})
+			return
+		}
+
+		bytes, err := context.GetRawData()
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "The request body must be a valid gradle.lockfile"})
+			return
+		}
+
+		artifacts := parseGradleDependencies(string(bytes))
+		if len(artifacts) == 0 {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "No runtimeClasspath dependencies found"})
+			return
+		}
+
+		req, err := parseRuntimeQuery(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		req.Artifacts = append(req.Artifacts, artifacts...)
+		req.ArtifactModules = true
+		req.Resolved = true
+		handleRequest(context, req)
+	})
+
+	// An endpoint for runtime requests containing application JAR files
+	router.POST("/runtime/:arch/:os/:version/jars", func(context *gin.Context) {
+		req, err := parseRuntimeQuery(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		if err := validateRequest(&req); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		jars, dir, err := saveUploadedJars(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		modules, err := detectModules(req.Vendor, req.Implementation, req.Version, req.ReleaseType, jars)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		req.Modules = normalizeModules(append(req.Modules, modules...))
+		handleRequest(context, req)
+	})
+
+	// An endpoint for detecting the modules required by application JAR files
+	router.POST("/modules/:version", func(context *gin.Context) {
 		var (
-			arch     = context.Param("arch")
-			endian   = context.Query("endian")
-			impl     = context.DefaultQuery("implementation", "hotspot")
-			platform = context.Param("os")
-			version  = context.Param("version")
+			impl    = context.DefaultQuery("implementation", "hotspot")
+			vendor  = context.DefaultQuery("vendor", DEFAULT_VENDOR)
+			version = context.Param("version")
 		)
 
-		var artifacts []string
-		if a := context.Query("artifacts"); a != "" {
-			if MAVEN_CENTRAL {
-				artifacts = strings.Split(a, ",")
-			} else {
-				context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven Central integration is disabled"})
-				return
-			}
+		releaseType, err := validateVersion(version, context.Query("release_type"))
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		if impl != "hotspot" && impl != "openj9" {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Valid implementation types: [hotspot, openj9]"})
+			return
+		}
+
+		if _, exists := jdkProviders[vendor]; !exists {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": invalidVendor().Error()})
+			return
+		}
+
+		jars, dir, err := saveUploadedJars(context)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
 		}
+		defer os.RemoveAll(dir)
 
-		handleRequest(context, platform, arch, version, endian, impl, parseModuleInfo(string(bytes)), artifacts)
+		modules, err := detectModules(vendor, impl, version, releaseType, jars)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		context.JSON(http.StatusOK, gin.H{"success": true, "modules": modules})
+	})
+
+	// An endpoint for asynchronous runtime requests (JSON)
+	router.POST("/builds", func(context *gin.Context) {
+		req := runtimeRequest{Options: defaultJlinkOptions()}
+
+		err := context.BindJSON(&req)
+		if err != nil {
+			return
+		}
+
+		if !MAVEN_INTEGRATION && len(req.Artifacts) > 0 {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Maven integration is disabled"})
+			return
+		}
+
+		if err := validateRequest(&req); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		job, err := submitBuild(req)
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "reason": err.Error()})
+			return
+		}
+
+		context.Header("Location", "/builds/"+job.ID)
+		context.JSON(http.StatusAccepted, gin.H{"success": true, "id": job.ID, "status": job.Status})
+	})
+
+	// An endpoint for the status of asynchronous runtime requests
+	router.GET("/builds/:id", func(context *gin.Context) {
+		job, exists := lookupBuild(context.Param("id"))
+		if !exists {
+			context.JSON(http.StatusNotFound, gin.H{"success": false, "reason": "Build not found"})
+			return
+		}
+
+		context.JSON(http.StatusOK, gin.H{"success": true, "build": job})
+	})
+
+	// An endpoint for the result of asynchronous runtime requests
+	router.GET("/builds/:id/artifact", func(context *gin.Context) {
+		job, exists := lookupBuild(context.Param("id"))
+		if !exists {
+			context.JSON(http.StatusNotFound, gin.H{"success": false, "reason": "Build not found"})
+			return
+		}
+
+		switch job.Status {
+		case buildDone:
+			serveArchive(context, job.archive)
+		case buildFailed:
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": job.Reason})
+		default:
+			context.JSON(http.StatusConflict, gin.H{"success": false, "reason": "Build is not finished"})
+		}
 	})
 
 	router.Run(":" + PORT)
@@ -225,199 +615,436 @@ func main() {
 
 var (
 	archCheck     = regexp.MustCompile(`^(x64|x32|ppc64|s390x|ppc64le|aarch64|arm)$`)
-	artifactCheck = regexp.MustCompile(`^[\w\.-]+:[\w\.-]+:[\w\.-]+$`)
+	artifactCheck = regexp.MustCompile(`^[\w\.-]+:[\w\.-]+:[\w\.-]+(:[\w\.-]+)?(@[\w\.-]+)?$`)
+	launcherCheck = regexp.MustCompile(`^([\w-]+)=([\w\.]+)(/[\w\.$]+)?$`)
 	moduleCheck   = regexp.MustCompile(`^[\w\.]+$`)
 	platformCheck = regexp.MustCompile(`^(linux|windows|mac|solaris|aix)$`)
 	versionCheck  = regexp.MustCompile(`^[1-9][0-9]*((\.0)*\.[1-9][0-9]*)*(\+[1-9][0-9]*((\.0)*\.[1-9][0-9]*)*)?$`)
+	aliasCheck    = regexp.MustCompile(`^(latest|lts|[1-9][0-9]*(-latest)?)$`)
+
+	// Early-access versions have a pre-release identifier like 22-ea+5
+	eaVersionCheck = regexp.MustCompile(`^[1-9][0-9]*((\.0)*\.[1-9][0-9]*)*-[a-zA-Z0-9]+(\+[1-9][0-9]*((\.0)*\.[1-9][0-9]*)*)?$`)
 )
 
-func handleRequest(context *gin.Context, platform, arch, version, endian, implementation string, modules, artifacts []string) {
+// A buildError describes a failure in the build pipeline along with a reason
+// that can be reported to the client.
+type buildError struct {
+	Reason string
+	Err    error
+}
 
-	// Validate platform type
-	if !platformCheck.MatchString(platform) {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Valid operating systems: [windows, linux, mac, solaris, aix]"})
+func (e *buildError) Error() string {
+	return e.Reason
+}
+
+func (e *buildError) Unwrap() error {
+	return e.Err
+}
+
+// NewBuildError wraps an error with a reason for the client. Errors which
+// explain themselves to the client (like checksum mismatches or content which
+// isn't available offline) are reported as they are.
+func newBuildError(reason string, err error) *buildError {
+	var checksum *checksumError
+	var offline *offlineError
+	if errors.As(err, &checksum) {
+		reason = checksum.Reason
+	} else if errors.As(err, &offline) {
+		reason = offline.Error()
+	}
+
+	return &buildError{reason, err}
+}
+
+// ParseRuntimeQuery reads a runtime request from the path and query parameters.
+func parseRuntimeQuery(context *gin.Context) (runtimeRequest, error) {
+	req := runtimeRequest{
+		Arch:           context.Param("arch"),
+		Endian:         context.Query("endian"),
+		Implementation: context.DefaultQuery("implementation", "hotspot"),
+		Vendor:         context.DefaultQuery("vendor", DEFAULT_VENDOR),
+		ReleaseType:    context.Query("release_type"),
+		Platform:       context.Param("os"),
+		Version:        context.Param("version"),
+		Modules:        strings.Split(context.DefaultQuery("modules", "java.base"), ","),
+		Format:         requestFormat(context, context.Query("format")),
+	}
+
+	options, err := parseJlinkOptions(context)
+	if err != nil {
+		return req, err
+	}
+	req.Options = options
+
+	if l := context.Query("launchers"); l != "" {
+		req.Launchers = strings.Split(l, ",")
+	}
+
+	if a := context.Query("artifacts"); a != "" {
+		if MAVEN_INTEGRATION {
+			req.Artifacts = strings.Split(a, ",")
+		} else {
+			return req, errors.New("Maven integration is disabled")
+		}
+	}
+
+	return req, nil
+}
+
+func handleRequest(context *gin.Context, req runtimeRequest) {
+
+	if err := validateRequest(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
 		return
 	}
 
-	// Validate architecture type
-	if !archCheck.MatchString(arch) {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Valid architectures: [x64, x32, ppc64, s390x, ppc64le, aarch64, arm]"})
+	archive, err := buildRuntime(&req, func(string) {})
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": err.Error()})
+		return
+	}
+
+	serveArchive(context, archive)
+}
+
+// AuthorizeAdmin checks the bearer token of a request for an administrative
+// endpoint and responds with an error if it doesn't match ADMIN_TOKEN.
+// Administrative endpoints are disabled unless ADMIN_TOKEN is set.
+func authorizeAdmin(context *gin.Context) bool {
+	if ADMIN_TOKEN == "" {
+		context.JSON(http.StatusNotFound, gin.H{"success": false, "reason": "Administrative endpoints are disabled"})
+		return false
+	}
+
+	token, bearer := strings.CutPrefix(context.GetHeader("Authorization"), "Bearer ")
+	if !bearer || subtle.ConstantTimeCompare([]byte(token), []byte(ADMIN_TOKEN)) != 1 {
+		context.JSON(http.StatusUnauthorized, gin.H{"success": false, "reason": "Invalid admin token"})
+		return false
+	}
+
+	return true
+}
+
+// ServeArchive streams a runtime archive from disk in the response.
+func serveArchive(context *gin.Context, archive *runtimeArchive) {
+	f, err := os.Open(archive.Path)
+	if os.IsNotExist(err) {
+		context.JSON(http.StatusNotFound, gin.H{"success": false, "reason": "Runtime was evicted from the cache"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"success": false, "reason": "Failed to read runtime"})
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"success": false, "reason": "Failed to read runtime"})
+		log.Println(err)
 		return
 	}
 
+	context.Header("Content-Type", archiveContentType(archive.Filename))
+	context.Header("Content-Disposition", "attachment; filename=\""+archive.Filename+"\"")
+	context.Header("X-Cache", archive.cacheStatus())
+	if archive.Version != "" {
+		context.Header("X-Java-Version", archive.Version)
+	}
+
+	// Handles Content-Length and range requests without reading the whole archive into memory
+	http.ServeContent(context.Writer, context.Request, archive.Filename, info.ModTime(), f)
+}
+
+// ValidateRequest checks the attributes of a runtime request and fills in any
+// values that can be guessed.
+func validateRequest(req *runtimeRequest) error {
+
+	// Validate platform type
+	if !platformCheck.MatchString(req.Platform) {
+		return errors.New("Valid operating systems: [windows, linux, mac, solaris, aix]")
+	}
+
+	// Validate architecture type
+	if !archCheck.MatchString(req.Arch) {
+		return errors.New("Valid architectures: [x64, x32, ppc64, s390x, ppc64le, aarch64, arm]")
+	}
+
 	// Validate artifacts
-	for _, artifact := range artifacts {
+	for _, artifact := range req.Artifacts {
 		if !artifactCheck.MatchString(artifact) {
-			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Invalid artifact"})
-			return
+			return errors.New("Invalid artifact")
 		}
 	}
 
 	// Validate modules
-	for _, module := range modules {
+	for _, module := range req.Modules {
 		if !moduleCheck.MatchString(module) {
-			context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Invalid module"})
-			return
+			return errors.New("Invalid module")
+		}
+	}
+
+	// Validate launchers
+	names := make(map[string]bool)
+	for _, launcher := range req.Launchers {
+		match := launcherCheck.FindStringSubmatch(launcher)
+		if match == nil {
+			return errors.New("Invalid launcher (expected name=module/mainclass): " + launcher)
+		}
+		if names[match[1]] {
+			return errors.New("Duplicate launcher name: " + match[1])
 		}
+		names[match[1]] = true
+	}
+
+	// Launchers are scripts which run bin/java
+	if len(req.Launchers) > 0 && req.Options.StripNativeCommands {
+		return errors.New("Launchers can't be combined with strip_native_commands")
 	}
 
 	// Validate endian type
-	if endian == "" {
+	if req.Endian == "" {
 		// Guess according to supplied architecture
-		if arch == "ppc64" || arch == "s390x" {
-			endian = "big"
+		if req.Arch == "ppc64" || req.Arch == "s390x" {
+			req.Endian = "big"
 		} else {
-			endian = "little"
+			req.Endian = "little"
 		}
 	}
-	if endian != "big" && endian != "little" {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Valid endian types: [little, big]"})
-		return
+	if req.Endian != "big" && req.Endian != "little" {
+		return errors.New("Valid endian types: [little, big]")
 	}
 
 	// Validate implementation
-	if implementation != "hotspot" && implementation != "openj9" {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Valid implementation types: [hotspot, openj9]"})
-		return
+	if req.Implementation != "hotspot" && req.Implementation != "openj9" {
+		return errors.New("Valid implementation types: [hotspot, openj9]")
 	}
 
-	// Validate version number
-	if !versionCheck.MatchString(version) {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Invalid Java version"})
-		return
+	// Validate vendor
+	if req.Vendor == "" {
+		req.Vendor = DEFAULT_VENDOR
+	}
+	if _, exists := jdkProviders[req.Vendor]; !exists {
+		return invalidVendor()
 	}
 
-	// Validate major version number
-	majorVersion, err := getMajorVersion(version)
-	if err != nil || majorVersion < 9 {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Invalid Java version"})
-		return
+	// Validate version number or alias and release type
+	releaseType, err := validateVersion(req.Version, req.ReleaseType)
+	if err != nil {
+		return err
+	}
+	req.ReleaseType = releaseType
+
+	// The major version of latest and lts is only known once they're resolved
+	if req.Version != "latest" && req.Version != "lts" {
+		majorVersion, err := getMajorVersion(req.Version)
+		if err != nil || majorVersion < 9 {
+			return errors.New("Invalid Java version")
+		}
+
+		// Validate jlink options
+		if err := req.Options.validate(majorVersion); err != nil {
+			return err
+		}
+	}
+
+	// Validate archive format
+	if _, exists := archiveFormats[req.Format]; req.Format != "" && !exists {
+		return errors.New("Valid formats: [tar.gz, zip, tar.xz, tar.zst]")
+	}
+
+	return nil
+}
+
+// ValidateVersion checks a version number or alias along with its release type
+// and returns the release type, which defaults to that of the version.
+func validateVersion(version, releaseType string) (string, error) {
+	if releaseType == "" {
+		releaseType = releaseTypeOf(version)
+	}
+	if releaseType != "ga" && releaseType != "ea" {
+		return "", errors.New("Valid release types: [ga, ea]")
+	}
+
+	if !versionCheck.MatchString(version) && !isVersionAlias(version) {
+		if !eaVersionCheck.MatchString(version) {
+			return "", errors.New("Invalid Java version")
+		}
+		if releaseType != "ea" {
+			return "", errors.New("Early-access versions require release_type=ea")
+		}
 	}
 
+	return releaseType, nil
+}
+
+// BuildRuntime fetches everything required by a validated runtime request and
+// generates the runtime archive, unless an identical archive is already cached.
+// The status function is called as the build moves through each stage.
+func buildRuntime(req *runtimeRequest, status func(string)) (*runtimeArchive, error) {
+
+	status(buildDownloading)
+
 	// Lookup the target runtime whose modules will be packaged into a new runtime image
-	target, err := lookupRelease(arch, platform, implementation, version)
+	target, err := lookupJdk(req.Vendor, req.Arch, req.Platform, req.Implementation, req.Version, req.ReleaseType)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to find target runtime"})
-		return
+		return nil, newBuildError("Failed to find target runtime", err)
+	}
+
+	// Check the version which an alias resolved to
+	majorVersion, err := getMajorVersion(target.Version)
+	if err != nil || majorVersion < 9 {
+		return nil, &buildError{"Invalid Java version", err}
+	}
+	if err := req.Options.validate(majorVersion); err != nil {
+		return nil, &buildError{err.Error(), err}
 	}
 
-	// Lookup a runtime containing a compatible version of jlink for local use
-	local, err := lookupRelease(LOCAL_ARCH, LOCAL_PLATFORM, implementation, version)
+	// Default to the format of the target runtime's package
+	if req.Format == "" {
+		req.Format = archiveFormat(target.Package.Name)
+	}
+
+	// Or the usual format of the target platform if it isn't an archive
+	if req.Format == "" {
+		req.Format = defaultArchiveFormat(req.Platform)
+	}
+	filename := archiveFilename(path.Base(target.Package.Name), req.Format)
+
+	// Create a directory for Maven artifacts
+	mavenArtifacts, dir := newTemporaryDirectory("mavenArtifacts")
+	defer os.RemoveAll(dir)
+
+	// Download any required artifacts
+	if err := downloadArtifacts(mavenArtifacts, req.Artifacts, req.Exclusions, req.Managed, !req.Resolved); err != nil {
+		log.Println(err)
+		return nil, newBuildError("Failed to download Maven artifacts", err)
+	}
+
+	// Check if an identical runtime was already generated
+	key, err := archiveKey(target.Vendor+"/"+target.Package.Name, req, mavenArtifacts)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to find local runtime"})
-		return
+		log.Println(err)
+		return nil, &buildError{"Failed to generate runtime", err}
+	}
+	if archive, exists := lookupArchive(key, filename); exists {
+		archive.Version = target.Version
+		return archive, nil
+	}
+
+	// Lookup a runtime containing the same version of jlink for local use
+	local, err := lookupJdk(req.Vendor, LOCAL_ARCH, LOCAL_PLATFORM, req.Implementation, target.Version, req.ReleaseType)
+	if err != nil {
+		return nil, newBuildError("Failed to find local runtime", err)
 	}
 
 	// Download the local runtime
-	localRuntimePath, err := downloadRelease(local, version)
+	localRuntimePath, releaseLocal, err := downloadJdk(local)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to download local runtime"})
 		log.Println(err)
-		return
+		return nil, newBuildError("Failed to download local runtime", err)
 	}
+	defer releaseLocal()
 
 	// Download the target runtime
-	targetRuntimePath, err := downloadRelease(target, version)
+	targetRuntimePath, releaseTarget, err := downloadJdk(target)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to download target runtime"})
 		log.Println(err)
-		return
+		return nil, newBuildError("Failed to download target runtime", err)
 	}
+	defer releaseTarget()
 
-	// Create a directory for Maven Central artifacts
-	mavenCentral, dir := newTemporaryDirectory("mavenCentral")
-	defer os.RemoveAll(dir)
-
-	// Download any required artifacts
-	if err := downloadArtifacts(mavenCentral, artifacts); err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to download Maven Central artifacts"})
-		log.Println(err)
-		return
+	// Add the modules of the resolved artifacts
+	if req.ArtifactModules {
+		modules, err := describeModules(localRuntimePath, mavenArtifacts)
+		if err != nil {
+			log.Println(err)
+			return nil, &buildError{"Failed to read artifact modules", err}
+		}
+		req.Modules = append(req.Modules, modules...)
 	}
 
+	status(buildLinking)
+
 	// Run jlink on the target runtime
-	archive, err := jlink(localRuntimePath, mavenCentral, targetRuntimePath, endian, version, platform, target.Package.Name, modules)
+	output, outputDir, err := jlink(localRuntimePath, mavenArtifacts, targetRuntimePath, req.Endian, target.Version, req.Platform, req.Modules, req.Launchers, req.Options)
+	defer os.RemoveAll(outputDir)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"success": false, "reason": "Failed to generate runtime"})
 		log.Println(err)
-		return
+		return nil, &buildError{"Failed to generate runtime", err}
 	}
 
-	context.DataFromReader(http.StatusOK, int64(archive.Len()), "application/octet-stream", archive, map[string]string{
-		"Content-Disposition": "attachment; filename=\"" + target.Package.Name + "\"",
-		"Accept-Ranges":       "bytes",
+	status(buildArchiving)
+
+	archive, err := storeArchive(key, filename, func(path string) error {
+		return archiveRuntime(output, path)
 	})
+	if err != nil {
+		log.Println(err)
+		return nil, &buildError{"Failed to generate runtime", err}
+	}
+
+	archive.Version = target.Version
+	return archive, nil
 }
 
 // Jlink uses a standard JDK runtime to generate a custom runtime image
-// for the given set of modules.
-func jlink(jdk, mavenCentral, runtime, endian, version, platform, filename string, modules []string) (*bytes.Buffer, error) {
-
-	var modulePath, jlink string
-
-	// Add the base module if it's not there
-	base := false
-	for _, m := range modules {
-		if m == "java.base" {
-			base = true
-			break
+// for the given set of modules. It returns the path to the runtime image and
+// its temporary parent directory.
+func jlink(jdk, mavenArtifacts, runtime, endian, version, platform string, modules, launchers []string, options jlinkOptions) (string, string, error) {
+
+	var modulePath string
+
+	// Add the base module and the module of each launcher, which is found on the
+	// module path through the artifacts if it isn't part of the JDK
+	required := append([]string{}, modules...)
+	for _, launcher := range launchers {
+		if match := launcherCheck.FindStringSubmatch(launcher); match != nil {
+			required = append(required, match[2])
 		}
 	}
-	if !base {
-		modules = append(modules, "java.base")
-	}
+	modules = normalizeModules(required)
 
 	output, dir := newTemporaryFile("jdk-" + version)
-	defer os.RemoveAll(dir)
 
 	// Build module path according to target platform
 	switch platform {
 	case "mac":
 		_, err := os.Stat(filepath.FromSlash(runtime + "/Contents/Home/jmods"))
 		if err != nil {
-			return nil, err
+			return "", dir, err
 		}
 
-		modulePath = filepath.FromSlash(runtime + "/Contents/Home/jmods" + string(os.PathListSeparator) + mavenCentral)
+		modulePath = filepath.FromSlash(runtime + "/Contents/Home/jmods" + string(os.PathListSeparator) + mavenArtifacts)
 	case "windows":
 		_, err := os.Stat(filepath.FromSlash(runtime + "/jmods"))
 		if err != nil {
-			return nil, err
+			return "", dir, err
 		}
 
-		modulePath = filepath.FromSlash(runtime + "/jmods" + string(os.PathListSeparator) + mavenCentral)
+		modulePath = filepath.FromSlash(runtime + "/jmods" + string(os.PathListSeparator) + mavenArtifacts)
 	default:
 		_, err := os.Stat(filepath.FromSlash(runtime + "/jmods"))
 		if err != nil {
-			return nil, err
+			return "", dir, err
 		}
 
-		modulePath = filepath.FromSlash(runtime + "/jmods" + string(os.PathListSeparator) + mavenCentral)
+		modulePath = filepath.FromSlash(runtime + "/jmods" + string(os.PathListSeparator) + mavenArtifacts)
 	}
 
-	// Build jlink command according to local platform
-	switch LOCAL_PLATFORM {
-	case "mac":
-		jlink = filepath.FromSlash(jdk + "/Contents/Home/bin/jlink")
-	case "windows":
-		jlink = filepath.FromSlash(jdk + "/bin/jlink.exe")
-	default:
-		jlink = filepath.FromSlash(jdk + "/bin/jlink")
+	jlink, err := localTool(jdk, "jlink")
+	if err != nil {
+		return "", dir, err
 	}
 
-	if err := os.Chmod(jlink, os.ModePerm); err != nil {
-		return nil, err
+	args := options.args()
+	for _, launcher := range launchers {
+		// A launcher command for a module
+		args = append(args, "--launcher", launcher)
 	}
 
-	cmd := exec.Command(jlink,
-		// Share string constants
-		"--compress=1",
-		// Exclude headers
-		"--no-header-files",
-		// Exclude man pages
-		"--no-man-pages",
-		// Remove debug information
-		"--strip-debug",
+	cmd := exec.Command(jlink, append(args,
 		// The target endian-ness
 		"--endian", endian,
 		// The path where modules can be found
@@ -425,35 +1052,26 @@ func jlink(jdk, mavenCentral, runtime, endian, version, platform, filename strin
 		// The selected modules
 		"--add-modules", strings.Join(modules, ","),
 		// The output directory
-		"--output", output)
+		"--output", output)...)
 
 	log.Println("JLINK:", cmd.Args)
 	if err := cmd.Run(); err != nil {
-		return nil, err
+		return "", dir, err
 	}
 
-	archive, dir := newTemporaryFile(filename)
-	defer os.RemoveAll(dir)
+	return output, dir, nil
+}
+
+// ArchiveRuntime compresses a runtime image into the given archive file whose
+// format is determined by its extension.
+func archiveRuntime(output, archive string) error {
 
 	// Archiver can't handle the symlinks in /legal on windows
 	if LOCAL_PLATFORM == "windows" {
 		_ = os.RemoveAll(filepath.FromSlash(output + "/legal"))
 	}
 
-	if err := archiver.Archive([]string{output}, archive); err != nil {
-		return nil, err
-	}
-
-	f, err := os.Open(archive)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var buffer bytes.Buffer
-	buffer.ReadFrom(f)
-
-	return &buffer, nil
+	return archiver.Archive([]string{output}, archive)
 }
 
 func determineLocalPlatform() string {
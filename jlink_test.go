@@ -14,14 +14,17 @@ package main
 import (
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/mholt/archiver/v3"
 	"github.com/stretchr/testify/assert"
 )
@@ -163,14 +166,91 @@ func TestApi(t *testing.T) {
 	// Invalid module
 	assertRequestFailure(t, "http://localhost:8080/runtime/x64/windows/11.0.8+10?modules=123", 400)
 	assertRequestFailure(t, "http://localhost:8080/runtime/x64/windows/11.0.8+10?modules=&", 400)
+	// Invalid format
+	assertRequestFailure(t, "http://localhost:8080/runtime/x64/linux/11.0.8+10?format=rar", 400)
+	// Invalid launcher
+	assertRequestFailure(t, "http://localhost:8080/runtime/x64/linux/11.0.8+10?launchers=app:com.example", 400)
+	assertRequestFailure(t, "http://localhost:8080/runtime/x64/linux/11.0.8+10?launchers=app=com.example/com.example.Main&strip_native_commands=true", 400)
+
+	// Invalid asynchronous build
+	res, err := http.Post("http://localhost:8080/builds", "application/json", strings.NewReader(`{"arch": "a", "os": "linux", "version": "11.0.8+10"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode)
+	// Nonexistent asynchronous build
+	assertRequestFailure(t, "http://localhost:8080/builds/123", 404)
+	assertRequestFailure(t, "http://localhost:8080/builds/123/artifact", 404)
 
 	// Health check
-	res, err := http.Get("http://localhost:8080/status")
+	res, err = http.Get("http://localhost:8080/status")
 	assert.NoError(t, err)
 	assert.Equal(t, 200, res.StatusCode)
 	defer res.Body.Close()
 }
 
+func TestArchiveRuntime(t *testing.T) {
+	output, dir := newTemporaryDirectory("jdk-11.0.8+10")
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(filepath.FromSlash(output+"/bin"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.FromSlash(output+"/bin/java"), []byte("java"), os.ModePerm))
+
+	archive := filepath.FromSlash(dir + "/jdk.tar.gz")
+	assert.NoError(t, archiveRuntime(output, archive))
+
+	extracted := filepath.FromSlash(dir + "/extracted")
+	assert.NoError(t, archiver.Unarchive(archive, extracted))
+	contents, err := os.ReadFile(filepath.FromSlash(extracted + "/jdk-11.0.8+10/bin/java"))
+	assert.NoError(t, err)
+	assert.Equal(t, "java", string(contents))
+}
+
+func TestServeArchive(t *testing.T) {
+	path, dir := newTemporaryFile("jdk.tar.gz")
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.WriteFile(path, []byte("archive"), os.ModePerm))
+
+	recorder := httptest.NewRecorder()
+	context, _ := gin.CreateTestContext(recorder)
+	context.Request = httptest.NewRequest("GET", "/runtime/x64/linux/11.0.8+10", nil)
+
+	serveArchive(context, &runtimeArchive{Path: path, Filename: "jdk.tar.gz", Cached: true, Version: "11.0.8+10"})
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "7", recorder.Header().Get("Content-Length"))
+	assert.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+	assert.Equal(t, "11.0.8+10", recorder.Header().Get("X-Java-Version"))
+	assert.Equal(t, "attachment; filename=\"jdk.tar.gz\"", recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, "archive", recorder.Body.String())
+}
+
+func TestValidateLaunchers(t *testing.T) {
+	req := runtimeRequest{
+		Arch:           "x64",
+		Platform:       "linux",
+		Version:        "11.0.8+10",
+		Implementation: "hotspot",
+		Modules:        []string{"jdk.jshell"},
+		Options:        defaultJlinkOptions(),
+	}
+
+	req.Launchers = []string{"jshell=jdk.jshell/jdk.internal.jshell.tool.JShellToolProvider", "shell=jdk.jshell"}
+	assert.NoError(t, validateRequest(&req))
+
+	// Invalid format
+	req.Launchers = []string{"jshell:jdk.jshell"}
+	assert.Error(t, validateRequest(&req))
+
+	// Duplicate name
+	req.Launchers = []string{"jshell=jdk.jshell", "jshell=jdk.jshell"}
+	assert.Error(t, validateRequest(&req))
+
+	// The module of a launcher is added to the runtime, so it can come from an artifact
+	req.Launchers = []string{"app=com.example/com.example.Main"}
+	assert.NoError(t, validateRequest(&req))
+
+	// Launchers need bin/java
+	req.Options.StripNativeCommands = true
+	assert.EqualError(t, validateRequest(&req), "Launchers can't be combined with strip_native_commands")
+}
+
 func TestVersionRegex(t *testing.T) {
 	assert.True(t, versionCheck.MatchString("9"))
 	assert.True(t, versionCheck.MatchString("9+1"))
@@ -185,6 +265,51 @@ func TestVersionRegex(t *testing.T) {
 	assert.False(t, versionCheck.MatchString("9.+1"))
 	assert.False(t, versionCheck.MatchString(".9"))
 	assert.False(t, versionCheck.MatchString("09"))
+
+	assert.True(t, isVersionAlias("17"))
+	assert.True(t, isVersionAlias("17-latest"))
+	assert.True(t, isVersionAlias("latest"))
+	assert.True(t, isVersionAlias("lts"))
+	assert.False(t, isVersionAlias("17.0.1"))
+	assert.False(t, isVersionAlias("17-lts"))
+	assert.False(t, isVersionAlias("latest-17"))
+
+	assert.True(t, eaVersionCheck.MatchString("22-ea"))
+	assert.True(t, eaVersionCheck.MatchString("22-ea+5"))
+	assert.True(t, eaVersionCheck.MatchString("17.0.2-ea+3"))
+	assert.False(t, eaVersionCheck.MatchString("22"))
+	assert.False(t, eaVersionCheck.MatchString("22+5"))
+	assert.False(t, eaVersionCheck.MatchString("22-ea+"))
+	assert.False(t, eaVersionCheck.MatchString("22-ea-beta+5"))
+}
+
+func TestValidateVersion(t *testing.T) {
+	releaseType, err := validateVersion("17.0.1+12", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "ga", releaseType)
+
+	// The release type of early-access versions is implied
+	releaseType, err = validateVersion("22-ea+5", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "ea", releaseType)
+
+	// Aliases are GA versions unless early-access builds are requested
+	for _, alias := range []string{"17", "17-latest", "latest", "lts"} {
+		releaseType, err = validateVersion(alias, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "ga", releaseType, alias)
+	}
+
+	releaseType, err = validateVersion("latest", "ea")
+	assert.NoError(t, err)
+	assert.Equal(t, "ea", releaseType)
+
+	_, err = validateVersion("22-ea+5", "ga")
+	assert.EqualError(t, err, "Early-access versions require release_type=ea")
+	_, err = validateVersion("17", "nightly")
+	assert.EqualError(t, err, "Valid release types: [ga, ea]")
+	_, err = validateVersion("22-ea+", "ea")
+	assert.EqualError(t, err, "Invalid Java version")
 }
 
 func TestDetermineLocalPlatform(t *testing.T) {
@@ -205,3 +330,33 @@ func TestDetermineLocalPlatform(t *testing.T) {
 	os.Setenv("LOCAL_PLATFORM", "mydiyos")
 	assert.Equal(t, "mydiyos", determineLocalPlatform())
 }
+
+func TestAuthorizeAdmin(t *testing.T) {
+	defer func(token string) { ADMIN_TOKEN = token }(ADMIN_TOKEN)
+
+	authorize := func(header string) (bool, int) {
+		recorder := httptest.NewRecorder()
+		context, _ := gin.CreateTestContext(recorder)
+		context.Request = httptest.NewRequest("DELETE", "/metadata", nil)
+		if header != "" {
+			context.Request.Header.Set("Authorization", header)
+		}
+		return authorizeAdmin(context), recorder.Code
+	}
+
+	// Administrative endpoints are disabled without a token
+	ADMIN_TOKEN = ""
+	authorized, code := authorize("Bearer ")
+	assert.False(t, authorized)
+	assert.Equal(t, 404, code)
+
+	ADMIN_TOKEN = "secret"
+	authorized, _ = authorize("Bearer secret")
+	assert.True(t, authorized)
+
+	for _, header := range []string{"", "secret", "Bearer other"} {
+		authorized, code = authorize(header)
+		assert.False(t, authorized)
+		assert.Equal(t, 401, code)
+	}
+}
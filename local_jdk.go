@@ -0,0 +1,305 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The vendor name of JDKs from LOCAL_JDKS
+const localVendor = "local"
+
+// The platform names used in JDK release files
+var releasePlatforms = map[string]string{
+	"Linux":   "linux",
+	"Windows": "windows",
+	"Darwin":  "mac",
+	"SunOS":   "solaris",
+	"AIX":     "aix",
+}
+
+// The architecture names used in JDK release files
+var releaseArchitectures = map[string]string{
+	"x86_64":  "x64",
+	"amd64":   "x64",
+	"x86":     "x32",
+	"i386":    "x32",
+	"i586":    "x32",
+	"i686":    "x32",
+	"aarch64": "aarch64",
+	"arm64":   "aarch64",
+	"arm":     "arm",
+	"ppc64":   "ppc64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// Matches runtime versions like 17.0.1+12-LTS or 23-ea+10-2024 along with the
+// version, pre-release identifier, build number and optional suffix
+var runtimeVersionCheck = regexp.MustCompile(`^([1-9][0-9.]*)(-[a-zA-Z0-9]+)?(\+[0-9.]+)?(-.*)?$`)
+
+// How long the JDKs found in the directory tree are used before searching again
+const localScanInterval = 5 * time.Minute
+
+// LocalProvider provides pre-provisioned JDKs from a directory tree. JDKs are
+// identified by their release files and are used in place.
+type localProvider struct {
+
+	// The directory to search for JDKs
+	root string
+
+	// The JDKs found by the last search and when it happened
+	sync.Mutex
+	jdks    []localJdk
+	scanned time.Time
+}
+
+// NewLocalProvider creates a provider for the JDKs in a directory tree which is
+// searched immediately.
+func newLocalProvider(root string) *localProvider {
+	provider := &localProvider{root: root}
+	if jdks, err := provider.available(); err != nil {
+		log.Println(err)
+	} else {
+		log.Printf("Found %d JDKs in %s", len(jdks), root)
+	}
+	return provider
+}
+
+// LocalJdk is a JDK which was found in the directory tree.
+type localJdk struct {
+
+	// The runtime version including the build number if known
+	runtimeVersion string
+
+	// Whether the JDK is a long-term support release
+	lts bool
+
+	release jdkRelease
+}
+
+// ExactVersion returns the most specific version of the JDK.
+func (jdk *localJdk) exactVersion() string {
+	if jdk.runtimeVersion != "" {
+		return jdk.runtimeVersion
+	}
+	return jdk.release.Version
+}
+
+// Lookup finds the first JDK in the directory tree with the given attributes.
+// Version aliases resolve to the newest matching JDK.
+func (provider *localProvider) lookup(arch, platform, implementation, version, releaseType string) (*jdkRelease, error) {
+	jdks, err := provider.available()
+	if err != nil {
+		return nil, err
+	}
+
+	if isVersionAlias(version) {
+		var newest *localJdk
+		for i, jdk := range jdks {
+			if jdk.release.Architecture != arch || jdk.release.Platform != platform || jdk.release.Implementation != implementation ||
+				jdk.release.ReleaseType != releaseType {
+				continue
+			}
+
+			major, _ := getMajorVersion(jdk.release.Version)
+			if (version == "lts" && !jdk.lts) || (aliasFeature(version) != 0 && aliasFeature(version) != major) {
+				continue
+			}
+			if newest == nil || compareVersions(jdk.exactVersion(), newest.exactVersion()) > 0 {
+				newest = &jdks[i]
+			}
+		}
+		if newest == nil {
+			return nil, errors.New("No release found")
+		}
+
+		release := newest.release
+		release.Version = newest.exactVersion()
+		return &release, nil
+	}
+
+	for _, jdk := range jdks {
+		if jdk.release.Architecture == arch && jdk.release.Platform == platform && jdk.release.Implementation == implementation &&
+			jdk.release.ReleaseType == releaseType && (jdk.release.Version == version || jdk.runtimeVersion == version) {
+			release := jdk.release
+			release.Version = version
+			return &release, nil
+		}
+	}
+
+	return nil, errors.New("No release found")
+}
+
+// Download returns the JDK directory since local JDKs are never copied or removed.
+func (provider *localProvider) download(release *jdkRelease) (string, func(), error) {
+	path := filepath.Join(provider.root, filepath.FromSlash(release.Package.Name))
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, err
+	}
+
+	return path, func() {}, nil
+}
+
+// Available returns the JDKs in the directory tree. The tree is only searched
+// again once the previous search is older than localScanInterval.
+func (provider *localProvider) available() ([]localJdk, error) {
+	provider.Lock()
+	defer provider.Unlock()
+
+	if !provider.scanned.IsZero() && time.Since(provider.scanned) < localScanInterval {
+		return provider.jdks, nil
+	}
+
+	jdks, err := provider.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	provider.jdks = jdks
+	provider.scanned = time.Now()
+	return jdks, nil
+}
+
+// Scan identifies every JDK in the directory tree. Directories which can't be
+// read are skipped.
+func (provider *localProvider) scan() ([]localJdk, error) {
+	var jdks []localJdk
+	err := filepath.WalkDir(provider.root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if path == provider.root {
+				return err
+			}
+			log.Printf("Skipping %s: %v", path, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+
+		properties, err := readReleaseFile(filepath.Join(path, "release"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			return filepath.SkipDir
+		}
+
+		// Mac JDKs are used through their bundle directory
+		home := path
+		if filepath.Base(path) == "Home" && filepath.Base(filepath.Dir(path)) == "Contents" {
+			home = filepath.Dir(filepath.Dir(path))
+		}
+
+		relative, err := filepath.Rel(provider.root, home)
+		if err != nil {
+			return err
+		}
+
+		jdk, err := identifyJdk(filepath.ToSlash(relative), properties)
+		if err != nil {
+			log.Printf("Ignoring JDK in %s: %v", path, err)
+		} else {
+			jdks = append(jdks, *jdk)
+		}
+
+		// JDKs don't contain other JDKs
+		return filepath.SkipDir
+	})
+
+	return jdks, err
+}
+
+// IdentifyJdk describes a JDK according to the properties in its release file.
+// The path of the JDK relative to the root becomes its package name.
+func identifyJdk(path string, properties map[string]string) (*localJdk, error) {
+	platform, exists := releasePlatforms[properties["OS_NAME"]]
+	if !exists {
+		return nil, errors.New("Unknown OS_NAME: " + properties["OS_NAME"])
+	}
+	arch, exists := releaseArchitectures[properties["OS_ARCH"]]
+	if !exists {
+		return nil, errors.New("Unknown OS_ARCH: " + properties["OS_ARCH"])
+	}
+	if properties["JAVA_VERSION"] == "" {
+		return nil, errors.New("Missing JAVA_VERSION")
+	}
+
+	implementation := "hotspot"
+	if strings.EqualFold(properties["JVM_VARIANT"], "openj9") || strings.Contains(strings.ToLower(properties["IMPLEMENTOR"]), "openj9") {
+		implementation = "openj9"
+	}
+
+	// Drop any suffix like -LTS from the runtime version, but keep the
+	// pre-release identifier of early-access builds
+	var runtimeVersion, suffix string
+	releaseType := "ga"
+	if match := runtimeVersionCheck.FindStringSubmatch(properties["JAVA_RUNTIME_VERSION"]); match != nil {
+		runtimeVersion, suffix = match[1]+match[2]+match[3], match[4]
+		if match[2] != "" {
+			releaseType = "ea"
+		}
+	}
+	major, _ := getMajorVersion(properties["JAVA_VERSION"])
+
+	return &localJdk{
+		runtimeVersion: runtimeVersion,
+		lts:            strings.HasPrefix(suffix, "-LTS") || ltsFeature(major),
+		release: jdkRelease{
+			Vendor:         localVendor,
+			Architecture:   arch,
+			Platform:       platform,
+			Implementation: implementation,
+			Version:        properties["JAVA_VERSION"],
+			ReleaseType:    releaseType,
+			Package:        jdkPackage{Name: path},
+		},
+	}, nil
+}
+
+// LtsFeature checks whether a feature version is a long-term support release
+// according to the OpenJDK release cadence. Some vendors also mark LTS releases
+// in their runtime version.
+func ltsFeature(feature int) bool {
+	return feature == 8 || feature == 11 || (feature >= 17 && (feature-17)%4 == 0)
+}
+
+// ReadReleaseFile reads the KEY="value" properties from a JDK release file.
+func readReleaseFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	properties := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key, value, found := strings.Cut(scanner.Text(), "="); found {
+			properties[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	return properties, scanner.Err()
+}
@@ -0,0 +1,135 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalProvider(t *testing.T) {
+	root := t.TempDir()
+	for path, release := range map[string]string{
+		"linux/jdk-17.0.1+12":                     "IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_RUNTIME_VERSION=\"17.0.1+12-LTS\"\nJAVA_VERSION=\"17.0.1\"\nOS_ARCH=\"x86_64\"\nOS_NAME=\"Linux\"\n",
+		"linux/jdk-23+10-ea":                      "JAVA_RUNTIME_VERSION=\"23-ea+10-2024-02-21\"\nJAVA_VERSION=\"23\"\nOS_ARCH=\"x86_64\"\nOS_NAME=\"Linux\"\n",
+		"linux/jdk-17.0.1+12-openj9":              "IMPLEMENTOR=\"IBM Corporation\"\nJAVA_VERSION=\"17.0.1\"\nJVM_VARIANT=\"Openj9\"\nOS_ARCH=\"amd64\"\nOS_NAME=\"Linux\"\n",
+		"mac/jdk-17.0.1+12.jdk/Contents/Home":     "JAVA_VERSION=\"17.0.1\"\nOS_ARCH=\"aarch64\"\nOS_NAME=\"Darwin\"\n",
+		"other/jdk-17.0.1+12":                     "JAVA_VERSION=\"17.0.1\"\nOS_ARCH=\"sparcv9\"\nOS_NAME=\"SunOS\"\n",
+		"linux/jdk-17.0.1+12/lib/nested/jdk-11.0": "JAVA_VERSION=\"11.0.8\"\nOS_ARCH=\"x86_64\"\nOS_NAME=\"Linux\"\n",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, filepath.FromSlash(path)), os.ModePerm))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, filepath.FromSlash(path), "release"), []byte(release), 0644))
+	}
+
+	provider := &localProvider{root: root}
+
+	// JDKs are found by version or runtime version
+	jdk, err := provider.lookup("x64", "linux", "hotspot", "17.0.1+12", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, &jdkRelease{
+		Vendor:         "local",
+		Architecture:   "x64",
+		Platform:       "linux",
+		Implementation: "hotspot",
+		Version:        "17.0.1+12",
+		ReleaseType:    "ga",
+		Package:        jdkPackage{Name: "linux/jdk-17.0.1+12"},
+	}, jdk)
+
+	path, release, err := provider.download(jdk)
+	assert.NoError(t, err)
+	release()
+	assert.Equal(t, filepath.Join(root, "linux", "jdk-17.0.1+12"), path)
+
+	// Tools of local JDKs are used without changing their permissions
+	assert.NoError(t, os.MkdirAll(filepath.Join(path, "bin"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(path, "bin", "jlink"), []byte("jlink"), 0755))
+	if LOCAL_PLATFORM == "linux" {
+		tool, err := localTool(path, "jlink")
+		assert.NoError(t, err)
+		info, err := os.Stat(tool)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	}
+
+	jdk, err = provider.lookup("x64", "linux", "openj9", "17.0.1", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "linux/jdk-17.0.1+12-openj9", jdk.Package.Name)
+
+	// Mac JDKs are used through their bundle
+	jdk, err = provider.lookup("aarch64", "mac", "hotspot", "17.0.1", "ga")
+	assert.NoError(t, err)
+	path, _, err = provider.download(jdk)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "mac", "jdk-17.0.1+12.jdk"), path)
+
+	// JDKs aren't searched for inside other JDKs and unknown platforms are ignored
+	_, err = provider.lookup("x64", "linux", "hotspot", "11.0.8", "ga")
+	assert.EqualError(t, err, "No release found")
+	_, err = provider.lookup("x64", "linux", "hotspot", "17.0.2", "ga")
+	assert.EqualError(t, err, "No release found")
+
+	// Aliases resolve to the newest JDK
+	jdk, err = provider.lookup("x64", "linux", "hotspot", "lts", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.0.1+12", jdk.Version)
+	jdk, err = provider.lookup("x64", "linux", "openj9", "17-latest", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.0.1", jdk.Version)
+	_, err = provider.lookup("x64", "linux", "hotspot", "11", "ga")
+	assert.EqualError(t, err, "No release found")
+
+	// Early-access JDKs are only found with the ea release type
+	jdk, err = provider.lookup("x64", "linux", "hotspot", "latest", "ea")
+	assert.NoError(t, err)
+	assert.Equal(t, "23-ea+10", jdk.Version)
+	_, err = provider.lookup("x64", "linux", "hotspot", "23-ea+10", "ea")
+	assert.NoError(t, err)
+	_, err = provider.lookup("x64", "linux", "hotspot", "23", "ga")
+	assert.EqualError(t, err, "No release found")
+
+	// JDKs which were removed can't be used
+	jdk, err = provider.lookup("aarch64", "mac", "hotspot", "latest", "ga")
+	assert.NoError(t, err)
+	assert.NoError(t, os.RemoveAll(filepath.Join(root, "mac")))
+	_, _, err = provider.download(jdk)
+	assert.Error(t, err)
+
+	// The directory tree isn't searched again until the interval passed
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "new", "jdk-21+35"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "new", "jdk-21+35", "release"), []byte("JAVA_VERSION=\"21\"\nOS_ARCH=\"x86_64\"\nOS_NAME=\"Linux\"\n"), 0644))
+	_, err = provider.lookup("x64", "linux", "hotspot", "21", "ga")
+	assert.EqualError(t, err, "No release found")
+	provider.scanned = time.Now().Add(-localScanInterval)
+	_, err = provider.lookup("x64", "linux", "hotspot", "21", "ga")
+	assert.NoError(t, err)
+}
+
+func TestLocalProviderUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Permissions aren't enforced for root")
+	}
+
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "jdk-17"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "jdk-17", "release"), []byte("JAVA_VERSION=\"17\"\nOS_ARCH=\"x86_64\"\nOS_NAME=\"Linux\"\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "private"), 0))
+	defer os.Chmod(filepath.Join(root, "private"), os.ModePerm)
+
+	// Unreadable directories are skipped
+	_, err := (&localProvider{root: root}).lookup("x64", "linux", "hotspot", "17", "ga")
+	assert.NoError(t, err)
+}
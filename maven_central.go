@@ -16,100 +16,313 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"strings"
 )
 
 type mavenPom struct {
-	Dependencies []mavenDependency `xml:"dependencies>dependency"`
+	GroupId              string            `xml:"groupId"`
+	ArtifactId           string            `xml:"artifactId"`
+	Version              string            `xml:"version"`
+	Parent               *mavenParent      `xml:"parent"`
+	Properties           mavenProperties   `xml:"properties"`
+	DependencyManagement []mavenDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies         []mavenDependency `xml:"dependencies>dependency"`
 }
 
-type mavenDependency struct {
+type mavenParent struct {
 	GroupId    string `xml:"groupId"`
 	ArtifactId string `xml:"artifactId"`
 	Version    string `xml:"version"`
-	Scope      string `xml:"scope"`
 }
 
-// DownloadArtifacts downloads artifacts and their dependencies from Maven Central.
-func downloadArtifacts(output string, artifacts []string) error {
-	for _, artifact := range artifacts {
-		gav := strings.Split(artifact, ":")
-		if len(gav) != 3 {
-			return errors.New("Invalid maven coordinates: " + artifact)
+type mavenDependency struct {
+	GroupId    string           `xml:"groupId"`
+	ArtifactId string           `xml:"artifactId"`
+	Version    string           `xml:"version"`
+	Scope      string           `xml:"scope"`
+	Type       string           `xml:"type"`
+	Classifier string           `xml:"classifier"`
+	Optional   string           `xml:"optional"`
+	Exclusions []mavenExclusion `xml:"exclusions>exclusion"`
+}
+
+type mavenExclusion struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+}
+
+// Artifact returns the Maven coordinates of the dependency.
+func (dep mavenDependency) artifact() mavenArtifact {
+	return mavenArtifact{dep.GroupId, dep.ArtifactId, dep.Version, dep.Classifier, dep.Type}
+}
+
+// IsRuntime checks whether the dependency is needed at runtime. Provided and
+// system dependencies are expected to exist already and test dependencies are
+// never needed.
+func (dep mavenDependency) isRuntime() bool {
+	return dep.Scope == "" || dep.Scope == "compile" || dep.Scope == "runtime"
+}
+
+// IsOptional checks whether the dependency is excluded from the dependencies of
+// artifacts which depend on the declaring artifact.
+func (dep mavenDependency) isOptional() bool {
+	return strings.TrimSpace(dep.Optional) == "true"
+}
+
+// Matches checks whether the exclusion applies to an artifact. Either part of
+// the exclusion may be a wildcard.
+func (exclusion mavenExclusion) matches(artifact mavenArtifact) bool {
+	return (exclusion.GroupId == "*" || exclusion.GroupId == artifact.GroupId) &&
+		(exclusion.ArtifactId == "*" || exclusion.ArtifactId == artifact.ArtifactId)
+}
+
+// MavenArtifact identifies an artifact by its Maven coordinates along with the
+// optional classifier and dependency type (jar if empty).
+type mavenArtifact struct {
+	GroupId    string
+	ArtifactId string
+	Version    string
+	Classifier string
+	Type       string
+}
+
+// The file extensions of the supported dependency types. The dependencies of a
+// pom dependency are used without downloading anything for the POM itself.
+var mavenTypeExtensions = map[string]string{
+	"":       "jar",
+	"jar":    "jar",
+	"bundle": "jar",
+	"pom":    "pom",
+}
+
+// ParseArtifact parses Maven coordinates in G:A:V[:classifier][@type] format.
+func parseArtifact(coordinates string) (mavenArtifact, error) {
+	gav, dependencyType, _ := strings.Cut(coordinates, "@")
+	parts := strings.Split(gav, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return mavenArtifact{}, errors.New("Invalid maven coordinates: " + coordinates)
+	}
+
+	artifact := mavenArtifact{parts[0], parts[1], parts[2], "", dependencyType}
+	if len(parts) == 4 {
+		artifact.Classifier = parts[3]
+	}
+	return artifact, nil
+}
+
+// String returns the coordinates of the artifact in G:A:V[:classifier][@type] format.
+func (artifact mavenArtifact) String() string {
+	coordinates := fmt.Sprintf("%s:%s:%s", artifact.GroupId, artifact.ArtifactId, artifact.Version)
+	if artifact.Classifier != "" {
+		coordinates += ":" + artifact.Classifier
+	}
+	if artifact.Type != "" && artifact.Type != "jar" {
+		coordinates += "@" + artifact.Type
+	}
+	return coordinates
+}
+
+// Key identifies the artifact regardless of its version. Like in Maven, the
+// classifier and type distinguish artifacts with the same G:A.
+func (artifact mavenArtifact) key() string {
+	key := artifact.GroupId + ":" + artifact.ArtifactId
+	if artifact.Type != "" && artifact.Type != "jar" {
+		key += ":" + artifact.Type
+	}
+	if artifact.Classifier != "" {
+		key += ":" + artifact.Classifier
+	}
+	return key
+}
+
+// Filename returns the name of one of the artifact's files. The POM is shared
+// by every classifier of an artifact.
+func (artifact mavenArtifact) filename(extension string) string {
+	if artifact.Classifier != "" && extension != "pom" {
+		return fmt.Sprintf("%s-%s-%s.%s", artifact.ArtifactId, artifact.Version, artifact.Classifier, extension)
+	}
+	return fmt.Sprintf("%s-%s.%s", artifact.ArtifactId, artifact.Version, extension)
+}
+
+// Path returns the location of one of the artifact's files relative to a repository.
+func (artifact mavenArtifact) path(extension string) string {
+	return fmt.Sprintf("/%s/%s/%s/%s", strings.ReplaceAll(artifact.GroupId, ".", "/"),
+		artifact.ArtifactId, artifact.Version, artifact.filename(extension))
+}
+
+// DownloadArtifacts downloads artifacts from the Maven repositories to the output
+// directory along with their dependencies if transitive is set. Exclusions
+// are keyed by the G:A of the artifact which declares them and managed
+// dependencies by their own G:A.
+func downloadArtifacts(output string, artifacts []string, exclusions map[string][]mavenExclusion, managed map[string]mavenDependency, transitive bool) error {
+	resolved, err := resolveArtifacts(artifacts, exclusions, managed, transitive)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range resolved {
+		dest := output + "/" + artifact.filename("jar")
+		if err := downloadArtifact(artifact, dest); err != nil {
+			return err
 		}
+	}
 
-		// Check if the artifact already exists
-		if _, e := os.Stat(fmt.Sprintf("%s/%s-%s.jar", output, gav[1], gav[2])); !os.IsNotExist(e) {
+	return nil
+}
+
+// ResolveArtifacts selects exactly one version of each artifact in the
+// dependency graph according to Maven's mediation rules: the nearest
+// declaration wins and the first declaration breaks ties. Only runtime
+// dependencies are followed, optional dependencies of the given artifacts are
+// skipped and an exclusion prunes the whole subtree below its declaration.
+// Dependencies of type pom are only followed for their own dependencies and
+// other types than jar are skipped. Like the <dependencyManagement> of the root POM, the managed dependencies
+// override the version and scope of transitive dependencies and add to their
+// exclusions before mediation.
+func resolveArtifacts(artifacts []string, exclusions map[string][]mavenExclusion, managed map[string]mavenDependency, transitive bool) ([]mavenArtifact, error) {
+
+	// A node in the dependency graph along with the exclusions of its ancestors
+	type node struct {
+		artifact   mavenArtifact
+		exclusions []mavenExclusion
+	}
+
+	// Visiting the graph breadth-first means the first version of an artifact
+	// to be dequeued is the nearest one (declared first at that depth)
+	var queue []node
+	for _, coordinates := range artifacts {
+		artifact, err := parseArtifact(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, node{artifact, exclusions[artifact.key()]})
+	}
+
+	selected := make(map[string]bool)
+
+	var resolved []mavenArtifact
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if selected[current.artifact.key()] {
 			continue
 		}
+		selected[current.artifact.key()] = true
 
-		base := fmt.Sprintf("https://repo1.maven.org/maven2/%s/%s/%s", strings.ReplaceAll(gav[0], ".", "/"), gav[1], gav[2])
-		err := downloadArtifact(fmt.Sprintf("%s/%s-%s.jar", base, gav[1], gav[2]), fmt.Sprintf("%s/%s-%s.jar", output, gav[1], gav[2]))
-		if err != nil {
-			return err
+		// Only JARs are downloaded, while pom dependencies contribute their dependencies
+		extension, supported := mavenTypeExtensions[current.artifact.Type]
+		if !supported {
+			log.Println("Skipping Maven artifact of unsupported type:", current.artifact)
+			continue
+		}
+		if extension == "jar" {
+			resolved = append(resolved, current.artifact)
 		}
 
-		pom, err := downloadPom(fmt.Sprintf("%s/%s-%s.pom", base, gav[1], gav[2]))
+		if !transitive {
+			continue
+		}
+
+		pom, err := loadPom(current.artifact, 0)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		var depArtifacts []string
+	dependencies:
 		for _, dep := range pom.Dependencies {
-			// Skip test dependencies
-			if dep.Scope == "test" {
+			if m, exists := managed[dep.artifact().key()]; exists {
+				if m.Version != "" {
+					dep.Version = m.Version
+				}
+				if m.Scope != "" {
+					dep.Scope = m.Scope
+				}
+				dep.Exclusions = append(append([]mavenExclusion{}, dep.Exclusions...), m.Exclusions...)
+			}
+			if !dep.isRuntime() || dep.isOptional() {
 				continue
 			}
-			depArtifacts = append(depArtifacts, fmt.Sprintf("%s:%s:%s", dep.GroupId, dep.ArtifactId, dep.Version))
-		}
 
-		if err := downloadArtifacts(output, depArtifacts); err != nil {
-			return err
+			dependency := dep.artifact()
+			for _, exclusion := range current.exclusions {
+				if exclusion.matches(dependency) {
+					continue dependencies
+				}
+			}
+
+			// Copy the inherited exclusions so siblings don't share them
+			inherited := append(append([]mavenExclusion{}, current.exclusions...), dep.Exclusions...)
+			queue = append(queue, node{dependency, inherited})
 		}
 	}
 
-	return nil
+	return resolved, nil
 }
 
-// DownloadPom downloads a POM file from Maven Central.
-func downloadPom(url string) (*mavenPom, error) {
-	log.Println("Downloading Maven Central POM:", url)
+// DownloadPom downloads and verifies the POM file of an artifact from the
+// Maven repositories.
+func downloadPom(artifact mavenArtifact) (*mavenPom, error) {
+	log.Println("Downloading Maven POM:", artifact)
 
-	response, err := mavenCentral.Get(url)
-	if err != nil {
+	buffer := new(bytes.Buffer)
+	if err := downloadMaven(artifact, "pom", buffer); err != nil {
 		return nil, err
 	}
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("Status Code: " + response.Status)
-	}
-	defer response.Body.Close()
 
-	buffer := new(bytes.Buffer)
-	buffer.ReadFrom(response.Body)
+	return parsePom(buffer.Bytes())
+}
 
+// ParsePom parses the contents of a POM file.
+func parsePom(data []byte) (*mavenPom, error) {
 	var pom mavenPom
-	if err := xml.Unmarshal(buffer.Bytes(), &pom); err != nil {
+	if err := xml.Unmarshal(data, &pom); err != nil {
 		return nil, err
 	}
 	return &pom, nil
 }
 
-// DownloadArtifact downloads an artifact from Maven Central to the filesystem.
-func downloadArtifact(url, dest string) error {
-	log.Println("Downloading Maven Central artifact:", url)
+// Artifacts returns the coordinates of the POM's runtime dependencies in G:A:V
+// format. Optional dependencies are included because they're declared directly.
+func (pom *mavenPom) artifacts() []string {
+	var artifacts []string
+	for _, dep := range pom.Dependencies {
+		if dep.isRuntime() {
+			artifacts = append(artifacts, dep.artifact().String())
+		}
+	}
 
-	response, err := mavenCentral.Get(url)
-	if err != nil {
-		return err
+	return artifacts
+}
+
+// Exclusions returns the exclusions of the POM's dependencies keyed by G:A.
+func (pom *mavenPom) exclusions() map[string][]mavenExclusion {
+	exclusions := make(map[string][]mavenExclusion)
+	for _, dep := range pom.Dependencies {
+		if len(dep.Exclusions) != 0 {
+			exclusions[dep.artifact().key()] = dep.Exclusions
+		}
 	}
-	if response.StatusCode != http.StatusOK {
-		return errors.New("Status Code: " + response.Status)
+
+	return exclusions
+}
+
+// Managed returns the POM's <dependencyManagement> keyed by G:A.
+func (pom *mavenPom) managed() map[string]mavenDependency {
+	managed := make(map[string]mavenDependency)
+	for _, dep := range pom.DependencyManagement {
+		managed[dep.artifact().key()] = dep
 	}
-	defer response.Body.Close()
+
+	return managed
+}
+
+// DownloadArtifact downloads and verifies the JAR of an artifact from the
+// Maven repositories to the filesystem.
+func downloadArtifact(artifact mavenArtifact, dest string) error {
+	log.Println("Downloading Maven artifact:", artifact)
 
 	out, err := os.Create(dest)
 	if err != nil {
@@ -117,8 +330,10 @@ func downloadArtifact(url, dest string) error {
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, response.Body)
-	if err != nil {
+	if err := downloadMaven(artifact, "jar", out); err != nil {
+		// Never leave an unverified JAR on the module path
+		out.Close()
+		os.Remove(dest)
 		return err
 	}
 
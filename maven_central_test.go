@@ -0,0 +1,264 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePom(t *testing.T) {
+	pom, err := parsePom([]byte(`
+		<project>
+			<groupId>com.github.example</groupId>
+			<artifactId>example</artifactId>
+			<version>1.0.0</version>
+			<dependencies>
+				<dependency>
+					<groupId>org.slf4j</groupId>
+					<artifactId>slf4j-api</artifactId>
+					<version>2.0.0-alpha1</version>
+				</dependency>
+				<dependency>
+					<groupId>org.junit.jupiter</groupId>
+					<artifactId>junit-jupiter-api</artifactId>
+					<version>5.7.0</version>
+					<scope>test</scope>
+				</dependency>
+				<dependency>
+					<groupId>javax.servlet</groupId>
+					<artifactId>javax.servlet-api</artifactId>
+					<version>4.0.1</version>
+					<scope>provided</scope>
+				</dependency>
+				<dependency>
+					<groupId>com.fasterxml.jackson.core</groupId>
+					<artifactId>jackson-databind</artifactId>
+					<version>2.12.1</version>
+					<optional>true</optional>
+					<exclusions>
+						<exclusion>
+							<groupId>com.fasterxml.jackson.core</groupId>
+							<artifactId>*</artifactId>
+						</exclusion>
+					</exclusions>
+				</dependency>
+			</dependencies>
+		</project>
+	`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org.slf4j:slf4j-api:2.0.0-alpha1", "com.fasterxml.jackson.core:jackson-databind:2.12.1"}, pom.artifacts())
+	assert.Equal(t, map[string][]mavenExclusion{
+		"com.fasterxml.jackson.core:jackson-databind": {{"com.fasterxml.jackson.core", "*"}},
+	}, pom.exclusions())
+
+	_, err = parsePom([]byte("module com.abc {}"))
+	assert.Error(t, err)
+}
+
+// Serves the given files from a fake Maven repository
+func newMavenRepository(files map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contents, exists := files[r.URL.Path]; exists {
+			fmt.Fprint(w, contents)
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// Returns a POM file with the given dependencies in G:A:V format
+func pomWithDependencies(artifacts ...string) string {
+	var dependencies strings.Builder
+	for _, artifact := range artifacts {
+		gav := strings.Split(artifact, ":")
+		fmt.Fprintf(&dependencies, "<dependency><groupId>%s</groupId><artifactId>%s</artifactId><version>%s</version></dependency>", gav[0], gav[1], gav[2])
+	}
+	return "<project><dependencies>" + dependencies.String() + "</dependencies></project>"
+}
+
+func TestResolveArtifacts(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		"/com/example/a/1/a-1.pom": pomWithDependencies("com.example:b:1", "com.example:c:1"),
+		"/com/example/b/1/b-1.pom": pomWithDependencies("com.example:d:2"),
+		"/com/example/c/1/c-1.pom": pomWithDependencies("com.example:d:1", "com.example:b:2"),
+		"/com/example/d/1/d-1.pom": pomWithDependencies(),
+		"/com/example/d/2/d-2.pom": pomWithDependencies(),
+		"/com/example/a/1/a-1.jar": "a",
+		"/com/example/b/1/b-1.jar": "b",
+		"/com/example/c/1/c-1.jar": "c",
+		"/com/example/d/2/d-2.jar": "d",
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+
+	// Nearest wins (b:1 over b:2), then first declaration (d:2 over d:1)
+	resolved, err := resolveArtifacts([]string{"com.example:a:1"}, nil, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenArtifact{
+		{"com.example", "a", "1", "", ""},
+		{"com.example", "b", "1", "", ""},
+		{"com.example", "c", "1", "", ""},
+		{"com.example", "d", "2", "", ""},
+	}, resolved)
+
+	// A direct dependency always wins (d:1 over d:2 from b)
+	resolved, err = resolveArtifacts([]string{"com.example:a:1", "com.example:d:1"}, nil, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenArtifact{
+		{"com.example", "a", "1", "", ""},
+		{"com.example", "d", "1", "", ""},
+		{"com.example", "b", "1", "", ""},
+		{"com.example", "c", "1", "", ""},
+	}, resolved)
+
+	output, dir := newTemporaryDirectory("mavenArtifacts")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, downloadArtifacts(output, []string{"com.example:a:1"}, nil, nil, true))
+	jars, err := filepath.Glob(filepath.Join(output, "*.jar"))
+	assert.NoError(t, err)
+	assert.Len(t, jars, 4)
+}
+
+func TestResolveArtifactsManaged(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		"/com/example/a/1/a-1.pom": pomWithDependencies("com.example:b:1", "com.example:c:1", "com.example:e:1"),
+		"/com/example/b/2/b-2.pom": pomWithDependencies(),
+		"/com/example/e/1/e-1.pom": pomWithDependencies("com.example:f:1", "com.example:g:1"),
+		"/com/example/g/1/g-1.pom": pomWithDependencies(),
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+
+	pom, err := parsePom([]byte(`
+		<project>
+			<dependencyManagement>
+				<dependencies>
+					<dependency><groupId>com.example</groupId><artifactId>b</artifactId><version>2</version></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>c</artifactId><version>1</version><scope>provided</scope></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>e</artifactId><version>1</version>
+						<exclusions><exclusion><groupId>com.example</groupId><artifactId>f</artifactId></exclusion></exclusions>
+					</dependency>
+				</dependencies>
+			</dependencyManagement>
+		</project>`))
+	assert.NoError(t, err)
+
+	// The root's managed version, scope and exclusions apply to transitive dependencies
+	resolved, err := resolveArtifacts([]string{"com.example:a:1"}, nil, pom.managed(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenArtifact{
+		{"com.example", "a", "1", "", ""},
+		{"com.example", "b", "2", "", ""},
+		{"com.example", "e", "1", "", ""},
+		{"com.example", "g", "1", "", ""},
+	}, resolved)
+}
+
+func TestResolveArtifactsTypes(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		"/com/example/a/1/a-1.pom": `
+			<project>
+				<dependencies>
+					<dependency><groupId>com.example</groupId><artifactId>p</artifactId><version>1</version><type>pom</type></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>n</artifactId><version>1</version><classifier>linux</classifier></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>w</artifactId><version>1</version><type>war</type></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>n</artifactId><version>1</version></dependency>
+				</dependencies>
+			</project>`,
+		"/com/example/p/1/p-1.pom":       pomWithDependencies("com.example:q:1"),
+		"/com/example/n/1/n-1.pom":       pomWithDependencies(),
+		"/com/example/q/1/q-1.pom":       pomWithDependencies(),
+		"/com/example/a/1/a-1.jar":       "a",
+		"/com/example/n/1/n-1.jar":       "n",
+		"/com/example/n/1/n-1-linux.jar": "n-linux",
+		"/com/example/q/1/q-1.jar":       "q",
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+
+	// A pom dependency only contributes its dependencies, a classifier is a
+	// separate artifact and unsupported types are skipped
+	resolved, err := resolveArtifacts([]string{"com.example:a:1"}, nil, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenArtifact{
+		{"com.example", "a", "1", "", ""},
+		{"com.example", "n", "1", "linux", ""},
+		{"com.example", "n", "1", "", ""},
+		{"com.example", "q", "1", "", ""},
+	}, resolved)
+
+	output, dir := newTemporaryDirectory("mavenArtifacts")
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, downloadArtifacts(output, []string{"com.example:a:1"}, nil, nil, true))
+	contents, err := os.ReadFile(filepath.Join(output, "n-1-linux.jar"))
+	assert.NoError(t, err)
+	assert.Equal(t, "n-linux", string(contents))
+
+	// Classifiers and types are part of the coordinates
+	artifact, err := parseArtifact("com.example:n:1:linux@pom")
+	assert.NoError(t, err)
+	assert.Equal(t, mavenArtifact{"com.example", "n", "1", "linux", "pom"}, artifact)
+	assert.Equal(t, "com.example:n:1:linux@pom", artifact.String())
+	assert.Equal(t, "/com/example/n/1/n-1.pom", artifact.path("pom"))
+	assert.True(t, artifactCheck.MatchString(artifact.String()))
+}
+
+func TestResolveArtifactsScopes(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		"/com/example/a/1/a-1.pom": `
+			<project>
+				<dependencies>
+					<dependency><groupId>com.example</groupId><artifactId>b</artifactId><version>1</version></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>c</artifactId><version>1</version><optional>true</optional></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>d</artifactId><version>1</version><scope>provided</scope></dependency>
+					<dependency><groupId>com.example</groupId><artifactId>e</artifactId><version>1</version><scope>runtime</scope>
+						<exclusions><exclusion><groupId>org.example</groupId><artifactId>*</artifactId></exclusion></exclusions>
+					</dependency>
+				</dependencies>
+			</project>`,
+		"/com/example/b/1/b-1.pom": pomWithDependencies("org.example:f:1", "com.example:g:1"),
+		"/com/example/e/1/e-1.pom": pomWithDependencies("com.example:h:1"),
+		"/com/example/h/1/h-1.pom": pomWithDependencies("org.example:i:1"),
+		"/com/example/g/1/g-1.pom": pomWithDependencies(),
+		"/org/example/f/1/f-1.pom": pomWithDependencies(),
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+
+	// Optional and provided dependencies are skipped and the exclusion on e
+	// prunes org.example:i from its subtree but not org.example:f from b's
+	resolved, err := resolveArtifacts([]string{"com.example:a:1"}, map[string][]mavenExclusion{
+		"com.example:a": {{"com.example", "g"}},
+	}, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenArtifact{
+		{"com.example", "a", "1", "", ""},
+		{"com.example", "b", "1", "", ""},
+		{"com.example", "e", "1", "", ""},
+		{"org.example", "f", "1", "", ""},
+		{"com.example", "h", "1", "", ""},
+	}, resolved)
+}
@@ -0,0 +1,125 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// The checksum sidecar files in order of preference
+var checksumAlgorithms = []struct {
+	extension string
+	hash      func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+}
+
+// ChecksumError means that a downloaded file couldn't be verified.
+type checksumError struct {
+	Reason string
+}
+
+func (e *checksumError) Error() string {
+	return e.Reason
+}
+
+// DownloadMaven downloads one of an artifact's files from the Maven
+// repositories into out and verifies it against the strongest checksum
+// published next to it in the same repository.
+func downloadMaven(artifact mavenArtifact, extension string, out io.Writer) error {
+	path := artifact.path(extension)
+
+	response, repository, err := fetchMaven(path)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	hashes := make([]hash.Hash, len(checksumAlgorithms))
+	writers := []io.Writer{out}
+	for i, algorithm := range checksumAlgorithms {
+		hashes[i] = algorithm.hash()
+		writers = append(writers, hashes[i])
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), response.Body); err != nil {
+		return err
+	}
+
+	for i, algorithm := range checksumAlgorithms {
+		expected, err := downloadChecksum(repository, path+"."+algorithm.extension)
+		if err != nil {
+			return err
+		}
+		if expected == "" {
+			continue
+		}
+
+		if actual := hex.EncodeToString(hashes[i].Sum(nil)); actual != expected {
+			return &checksumError{"Checksum mismatch for " + artifact.String() + " (" + extension + ")"}
+		}
+		return nil
+	}
+
+	if MAVEN_REQUIRE_CHECKSUMS {
+		return &checksumError{"No checksum found for " + artifact.String() + " (" + extension + ")"}
+	}
+	log.Println("No checksum found for Maven artifact:", path)
+	return nil
+}
+
+// DownloadChecksum downloads a checksum sidecar file from a repository. An
+// empty string is returned if the repository doesn't serve the file, since
+// some repositories respond with 403 rather than 404 for missing files.
+func downloadChecksum(repository mavenRepository, path string) (string, error) {
+	response, err := repository.get(path)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		if response.StatusCode != http.StatusNotFound {
+			log.Println("Checksum unavailable:", path, response.Status)
+		}
+		return "", nil
+	}
+
+	buffer := new(bytes.Buffer)
+	if _, err := buffer.ReadFrom(response.Body); err != nil {
+		return "", err
+	}
+
+	return parseChecksum(buffer.String()), nil
+}
+
+// ParseChecksum extracts the digest from a checksum file, which may be
+// followed by the filename like the output of sha1sum.
+func parseChecksum(contents string) string {
+	fields := strings.Fields(contents)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(fields[0])
+}
@@ -0,0 +1,94 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChecksum(t *testing.T) {
+	assert.Equal(t, "da39a3ee5e6b4b0d3255bfef95601890afd80709", parseChecksum("da39a3ee5e6b4b0d3255bfef95601890afd80709"))
+	assert.Equal(t, "da39a3ee5e6b4b0d3255bfef95601890afd80709", parseChecksum("DA39A3EE5E6B4B0D3255BFEF95601890AFD80709  a-1.jar\n"))
+	assert.Equal(t, "", parseChecksum(" \n"))
+}
+
+func TestDownloadMaven(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		// sha1("a") and sha256("a")
+		"/com/example/a/1/a-1.jar":        "a",
+		"/com/example/a/1/a-1.jar.sha1":   "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8",
+		"/com/example/a/1/a-1.jar.sha256": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a-1.jar",
+
+		// The preferred checksum doesn't match
+		"/com/example/b/1/b-1.jar":        "b",
+		"/com/example/b/1/b-1.jar.sha1":   "e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98",
+		"/com/example/b/1/b-1.jar.sha512": "0000",
+
+		// No checksum
+		"/com/example/c/1/c-1.jar": "c",
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+	defer func(require bool) { MAVEN_REQUIRE_CHECKSUMS = require }(MAVEN_REQUIRE_CHECKSUMS)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, downloadMaven(mavenArtifact{"com.example", "a", "1", "", ""}, "jar", out))
+	assert.Equal(t, "a", out.String())
+
+	err := downloadMaven(mavenArtifact{"com.example", "b", "1", "", ""}, "jar", new(bytes.Buffer))
+	assert.EqualError(t, err, "Checksum mismatch for com.example:b:1 (jar)")
+
+	MAVEN_REQUIRE_CHECKSUMS = false
+	assert.NoError(t, downloadMaven(mavenArtifact{"com.example", "c", "1", "", ""}, "jar", new(bytes.Buffer)))
+
+	MAVEN_REQUIRE_CHECKSUMS = true
+	err = downloadMaven(mavenArtifact{"com.example", "c", "1", "", ""}, "jar", new(bytes.Buffer))
+	assert.EqualError(t, err, "No checksum found for com.example:c:1 (jar)")
+
+	// Rejected JARs aren't left on the module path
+	output, dir := newTemporaryDirectory("mavenArtifacts")
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(output, "b-1.jar")
+	assert.Error(t, downloadArtifact(mavenArtifact{"com.example", "b", "1", "", ""}, dest))
+	assert.NoFileExists(t, dest)
+}
+
+func TestDownloadMavenForbiddenChecksum(t *testing.T) {
+	repository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/com/example/a/1/a-1.jar":
+			w.Write([]byte("a"))
+		case "/com/example/a/1/a-1.jar.sha1":
+			w.Write([]byte("86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"))
+		default:
+			// Like repositories which deny access to missing files
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}))
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+	defer func(require bool) { MAVEN_REQUIRE_CHECKSUMS = require }(MAVEN_REQUIRE_CHECKSUMS)
+	MAVEN_REQUIRE_CHECKSUMS = true
+
+	// Unavailable checksums fall through to the next algorithm
+	assert.NoError(t, downloadMaven(mavenArtifact{"com.example", "a", "1", "", ""}, "jar", new(bytes.Buffer)))
+}
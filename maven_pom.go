@@ -0,0 +1,227 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// The maximum length of a chain of parent POMs or BOM imports
+const maxPomDepth = 16
+
+// MavenProperties holds the <properties> of a POM file.
+type mavenProperties map[string]string
+
+// UnmarshalXML reads each child element of <properties> as a property.
+func (properties *mavenProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*properties = make(mavenProperties)
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			(*properties)[element.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// LoadPom downloads the POM file of an artifact and resolves it into an
+// effective POM. The depth is the number of POMs that led to this one.
+func loadPom(artifact mavenArtifact, depth int) (*mavenPom, error) {
+	if depth > maxPomDepth {
+		return nil, errors.New("POM hierarchy is too deep: " + artifact.String())
+	}
+
+	pom, err := downloadPom(artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolvePom(pom, depth); err != nil {
+		return nil, err
+	}
+	return pom, nil
+}
+
+// ResolvePom turns a POM into an effective POM by applying its parents,
+// interpolating properties and applying <dependencyManagement> (including
+// imported BOMs) to its dependencies.
+func resolvePom(pom *mavenPom, depth int) error {
+	if err := inheritPom(pom, depth); err != nil {
+		return err
+	}
+
+	interpolatePom(pom)
+
+	if err := importBoms(pom, depth); err != nil {
+		return err
+	}
+
+	managePom(pom)
+	return nil
+}
+
+// InheritPom merges the chain of parent POMs into the given POM. Values from
+// the child take precedence over values from its parents.
+func inheritPom(pom *mavenPom, depth int) error {
+	for parentRef := pom.Parent; parentRef != nil; {
+		depth++
+		if depth > maxPomDepth {
+			return errors.New("POM hierarchy is too deep: " + pom.GroupId + ":" + pom.ArtifactId)
+		}
+
+		parent, err := downloadPom(mavenArtifact{GroupId: parentRef.GroupId, ArtifactId: parentRef.ArtifactId, Version: parentRef.Version})
+		if err != nil {
+			return err
+		}
+
+		if pom.GroupId == "" {
+			pom.GroupId = parentRef.GroupId
+		}
+		if pom.Version == "" {
+			pom.Version = parentRef.Version
+		}
+
+		if pom.Properties == nil {
+			pom.Properties = make(mavenProperties)
+		}
+		for name, value := range parent.Properties {
+			if _, exists := pom.Properties[name]; !exists {
+				pom.Properties[name] = value
+			}
+		}
+
+		pom.DependencyManagement = mergeDependencies(pom.DependencyManagement, parent.DependencyManagement)
+		pom.Dependencies = mergeDependencies(pom.Dependencies, parent.Dependencies)
+
+		parentRef = parent.Parent
+	}
+
+	return nil
+}
+
+// MergeDependencies appends the inherited dependencies which aren't overridden.
+func mergeDependencies(dependencies, inherited []mavenDependency) []mavenDependency {
+	declared := make(map[string]bool)
+	for _, dep := range dependencies {
+		declared[dep.GroupId+":"+dep.ArtifactId] = true
+	}
+
+	for _, dep := range inherited {
+		if !declared[dep.GroupId+":"+dep.ArtifactId] {
+			dependencies = append(dependencies, dep)
+		}
+	}
+
+	return dependencies
+}
+
+var propertyReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// InterpolatePom replaces property references in the dependencies of a POM
+// with values from its <properties> or the built-in project properties.
+func interpolatePom(pom *mavenPom) {
+	properties := make(map[string]string)
+	for name, value := range pom.Properties {
+		properties[name] = value
+	}
+	for _, prefix := range []string{"project.", "pom.", ""} {
+		properties[prefix+"groupId"] = pom.GroupId
+		properties[prefix+"artifactId"] = pom.ArtifactId
+		properties[prefix+"version"] = pom.Version
+	}
+	if pom.Parent != nil {
+		properties["project.parent.groupId"] = pom.Parent.GroupId
+		properties["project.parent.artifactId"] = pom.Parent.ArtifactId
+		properties["project.parent.version"] = pom.Parent.Version
+	}
+
+	interpolate := func(value string) string {
+		// Properties may refer to other properties
+		for i := 0; i < maxPomDepth && strings.Contains(value, "${"); i++ {
+			value = propertyReference.ReplaceAllStringFunc(value, func(reference string) string {
+				if property, exists := properties[reference[2:len(reference)-1]]; exists {
+					return property
+				}
+				return reference
+			})
+		}
+		return value
+	}
+
+	for _, dependencies := range [][]mavenDependency{pom.DependencyManagement, pom.Dependencies} {
+		for i := range dependencies {
+			dependencies[i].GroupId = interpolate(dependencies[i].GroupId)
+			dependencies[i].ArtifactId = interpolate(dependencies[i].ArtifactId)
+			dependencies[i].Version = interpolate(dependencies[i].Version)
+			dependencies[i].Scope = interpolate(dependencies[i].Scope)
+			dependencies[i].Optional = interpolate(dependencies[i].Optional)
+		}
+	}
+}
+
+// ImportBoms replaces the "import" entries in <dependencyManagement> with the
+// managed dependencies of the referenced BOMs.
+func importBoms(pom *mavenPom, depth int) error {
+	var managed, imported []mavenDependency
+	for _, dep := range pom.DependencyManagement {
+		if dep.Scope != "import" || dep.Type != "pom" {
+			managed = append(managed, dep)
+			continue
+		}
+
+		bom, err := loadPom(mavenArtifact{GroupId: dep.GroupId, ArtifactId: dep.ArtifactId, Version: dep.Version}, depth+1)
+		if err != nil {
+			return err
+		}
+		imported = append(imported, bom.DependencyManagement...)
+	}
+
+	pom.DependencyManagement = mergeDependencies(managed, imported)
+	return nil
+}
+
+// ManagePom fills in the versions, scopes and exclusions of dependencies from
+// <dependencyManagement>.
+func managePom(pom *mavenPom) {
+	managed := make(map[string]mavenDependency)
+	for _, dep := range pom.DependencyManagement {
+		managed[dep.GroupId+":"+dep.ArtifactId] = dep
+	}
+
+	for i, dep := range pom.Dependencies {
+		if m, exists := managed[dep.GroupId+":"+dep.ArtifactId]; exists {
+			if dep.Version == "" {
+				pom.Dependencies[i].Version = m.Version
+			}
+			if dep.Scope == "" {
+				pom.Dependencies[i].Scope = m.Scope
+			}
+			if len(dep.Exclusions) == 0 {
+				pom.Dependencies[i].Exclusions = m.Exclusions
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePom(t *testing.T) {
+	repository := newMavenRepository(map[string]string{
+		"/com/example/parent/1/parent-1.pom": `
+			<project>
+				<groupId>com.example</groupId>
+				<artifactId>parent</artifactId>
+				<version>1</version>
+				<properties>
+					<slf4j.version>1.7.30</slf4j.version>
+					<jackson.version>2.12.0</jackson.version>
+				</properties>
+				<dependencyManagement>
+					<dependencies>
+						<dependency>
+							<groupId>com.fasterxml.jackson.core</groupId>
+							<artifactId>jackson-core</artifactId>
+							<version>${jackson.version}</version>
+						</dependency>
+						<dependency>
+							<groupId>com.example</groupId>
+							<artifactId>bom</artifactId>
+							<version>1</version>
+							<type>pom</type>
+							<scope>import</scope>
+						</dependency>
+					</dependencies>
+				</dependencyManagement>
+				<dependencies>
+					<dependency>
+						<groupId>${project.groupId}</groupId>
+						<artifactId>common</artifactId>
+						<version>${project.version}</version>
+					</dependency>
+				</dependencies>
+			</project>`,
+		"/com/example/bom/1/bom-1.pom": `
+			<project>
+				<groupId>com.example</groupId>
+				<artifactId>bom</artifactId>
+				<version>1</version>
+				<dependencyManagement>
+					<dependencies>
+						<dependency>
+							<groupId>com.example</groupId>
+							<artifactId>managed</artifactId>
+							<version>3</version>
+						</dependency>
+					</dependencies>
+				</dependencyManagement>
+			</project>`,
+	})
+	defer repository.Close()
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "test", Url: repository.URL}}
+
+	pom, err := parsePom([]byte(`
+		<project>
+			<parent>
+				<groupId>com.example</groupId>
+				<artifactId>parent</artifactId>
+				<version>1</version>
+			</parent>
+			<artifactId>child</artifactId>
+			<properties>
+				<jackson.version>2.12.1</jackson.version>
+			</properties>
+			<dependencies>
+				<dependency>
+					<groupId>org.slf4j</groupId>
+					<artifactId>slf4j-api</artifactId>
+					<version>${slf4j.version}</version>
+				</dependency>
+				<dependency>
+					<groupId>com.fasterxml.jackson.core</groupId>
+					<artifactId>jackson-core</artifactId>
+				</dependency>
+				<dependency>
+					<groupId>com.example</groupId>
+					<artifactId>managed</artifactId>
+				</dependency>
+			</dependencies>
+		</project>`))
+	assert.NoError(t, err)
+	assert.NoError(t, resolvePom(pom, 0))
+
+	assert.Equal(t, "com.example", pom.GroupId)
+	assert.Equal(t, "1", pom.Version)
+	assert.Equal(t, []string{
+		"org.slf4j:slf4j-api:1.7.30",
+		"com.fasterxml.jackson.core:jackson-core:2.12.1",
+		"com.example:managed:3",
+		"com.example:common:1",
+	}, pom.artifacts())
+
+	// Missing parents are an error
+	pom, err = parsePom([]byte(`<project><parent><groupId>com.example</groupId><artifactId>missing</artifactId><version>1</version></parent></project>`))
+	assert.NoError(t, err)
+	assert.Error(t, resolvePom(pom, 0))
+}
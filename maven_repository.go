@@ -0,0 +1,128 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MavenRepository is a remote Maven repository along with its credentials.
+type mavenRepository struct {
+	Name string
+	Url  string
+
+	// Credentials for basic authentication
+	Username string
+	Password string
+
+	// A token for bearer authentication
+	Token string
+}
+
+// The default repository when none are configured
+var mavenCentralRepository = mavenRepository{Name: "central", Url: "https://repo1.maven.org/maven2"}
+
+var repositoryNameCheck = regexp.MustCompile(`^\w+$`)
+
+// ParseMavenRepositories parses an ordered, comma-separated list of repositories
+// in name=url format (file:// URLs refer to local repositories). The credentials
+// of each repository are read from the MAVEN_REPOSITORY_<NAME>_USERNAME and
+// _PASSWORD (or _TOKEN) environment variables.
+func parseMavenRepositories(value string) ([]mavenRepository, error) {
+	var repositories []mavenRepository
+	for _, entry := range strings.Split(value, ",") {
+		name, location, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || !repositoryNameCheck.MatchString(name) || !validRepositoryUrl(location) {
+			return nil, errors.New("Invalid Maven repository: " + entry)
+		}
+
+		prefix := "MAVEN_REPOSITORY_" + strings.ToUpper(name) + "_"
+		repositories = append(repositories, mavenRepository{
+			Name:     name,
+			Url:      strings.TrimSuffix(location, "/"),
+			Username: os.Getenv(prefix + "USERNAME"),
+			Password: os.Getenv(prefix + "PASSWORD"),
+			Token:    os.Getenv(prefix + "TOKEN"),
+		})
+	}
+
+	return repositories, nil
+}
+
+// ValidRepositoryUrl checks whether a repository URL is an absolute http://,
+// https:// or file:// URL.
+func validRepositoryUrl(location string) bool {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return parsed.Host != "" && strings.HasPrefix(location, parsed.Scheme+"://")
+	case "file":
+		return strings.HasPrefix(location, "file://")
+	}
+	return false
+}
+
+// Get requests a file from the repository with its credentials.
+func (repository mavenRepository) get(path string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, repository.Url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if repository.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+repository.Token)
+	} else if repository.Username != "" {
+		request.SetBasicAuth(repository.Username, repository.Password)
+	}
+
+	return mavenClient.Do(request)
+}
+
+// FetchMaven searches the configured repositories in order for a file and
+// returns the first successful response along with the repository that served
+// it. The caller must close its body.
+func fetchMaven(path string) (*http.Response, mavenRepository, error) {
+	var failures []string
+	for _, repository := range MAVEN_REPOSITORIES {
+		// Only local repositories can be used in offline mode
+		if OFFLINE && !strings.HasPrefix(repository.Url, "file://") {
+			continue
+		}
+
+		response, err := repository.get(path)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", repository.Name, err))
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			failures = append(failures, fmt.Sprintf("%s: %s", repository.Name, response.Status))
+			continue
+		}
+		return response, repository, nil
+	}
+
+	if OFFLINE {
+		return nil, mavenRepository{}, &offlineError{path}
+	}
+	return nil, mavenRepository{}, fmt.Errorf("%s not found in any Maven repository (%s)", path, strings.Join(failures, ", "))
+}
@@ -0,0 +1,97 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMavenRepositories(t *testing.T) {
+	t.Setenv("MAVEN_REPOSITORY_NEXUS_USERNAME", "user")
+	t.Setenv("MAVEN_REPOSITORY_NEXUS_PASSWORD", "secret")
+	t.Setenv("MAVEN_REPOSITORY_GITLAB_TOKEN", "token")
+
+	repositories, err := parseMavenRepositories("nexus=https://nexus.example.com/repository/maven/, gitlab=https://gitlab.example.com/maven,central=https://repo1.maven.org/maven2")
+	assert.NoError(t, err)
+	assert.Equal(t, []mavenRepository{
+		{Name: "nexus", Url: "https://nexus.example.com/repository/maven", Username: "user", Password: "secret"},
+		{Name: "gitlab", Url: "https://gitlab.example.com/maven", Token: "token"},
+		{Name: "central", Url: "https://repo1.maven.org/maven2"},
+	}, repositories)
+
+	_, err = parseMavenRepositories("https://repo1.maven.org/maven2")
+	assert.Error(t, err)
+	_, err = parseMavenRepositories("central=ftp://repo1.maven.org/maven2")
+	assert.Error(t, err)
+	_, err = parseMavenRepositories("central=httpfoo://repo1.maven.org/maven2")
+	assert.Error(t, err)
+	_, err = parseMavenRepositories("central=http:/repo1.maven.org/maven2")
+	assert.Error(t, err)
+	_, err = parseMavenRepositories("central=https://")
+	assert.Error(t, err)
+	_, err = parseMavenRepositories("local=file:///srv/maven")
+	assert.NoError(t, err)
+	_, err = parseMavenRepositories("")
+	assert.Error(t, err)
+}
+
+func TestFetchMaven(t *testing.T) {
+	// A private repository which requires basic authentication
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/private.pom" {
+			fmt.Fprint(w, "private")
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	defer private.Close()
+
+	// A repository which requires bearer authentication
+	bearer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "bearer")
+	}))
+	defer bearer.Close()
+
+	defer func(repositories []mavenRepository) { MAVEN_REPOSITORIES = repositories }(MAVEN_REPOSITORIES)
+	MAVEN_REPOSITORIES = []mavenRepository{
+		{Name: "private", Url: private.URL, Username: "user", Password: "secret"},
+		{Name: "bearer", Url: bearer.URL, Token: "token"},
+	}
+
+	// Repositories are searched in order
+	for path, expected := range map[string]string{"/private.pom": "private", "/public.pom": "bearer"} {
+		response, _, err := fetchMaven(path)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, expected, string(body))
+	}
+
+	// Credentials are required
+	MAVEN_REPOSITORIES = []mavenRepository{{Name: "private", Url: private.URL}, {Name: "bearer", Url: bearer.URL}}
+	_, _, err := fetchMaven("/private.pom")
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,197 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata entry types
+const (
+	// Release metadata for an exact version
+	metadataRelease = "release"
+
+	// A release which couldn't be found
+	metadataMissing = "missing"
+
+	// The release which a version alias resolves to
+	metadataAlias = "alias"
+)
+
+// The default time to live of each metadata entry type. Zero means that
+// entries of the type never expire.
+var defaultMetadataTTL = map[string]time.Duration{
+	metadataRelease: 0,
+	metadataMissing: 10 * time.Minute,
+	metadataAlias:   time.Hour,
+}
+
+// MetadataEntry is a cached value along with its expiry time.
+type metadataEntry struct {
+	Type    string          `json:"type"`
+	Value   json.RawMessage `json:"value"`
+	Expires *time.Time      `json:"expires,omitempty"`
+}
+
+// MetadataStore is a thread-safe cache for upstream metadata which is
+// optionally persisted to a file.
+type metadataStore struct {
+	sync.Mutex
+
+	// The file to persist entries to (entries are only kept in memory if empty)
+	path string
+
+	// The time to live of each entry type
+	ttl map[string]time.Duration
+
+	entries map[string]metadataEntry
+}
+
+// A cache for release metadata from the Adoptium API
+var metadataCache = newMetadataStore("", defaultMetadataTTL)
+
+// NewMetadataStore creates a metadata cache which persists to the given file
+// and loads any entries which were previously saved there.
+func newMetadataStore(path string, ttl map[string]time.Duration) *metadataStore {
+	store := &metadataStore{path: path, ttl: ttl, entries: make(map[string]metadataEntry)}
+	if path == "" {
+		return store
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		// A corrupt file is no worse than an empty cache
+		_ = json.Unmarshal(data, &store.entries)
+	}
+
+	return store
+}
+
+// ParseMetadataTTL parses a comma-separated list of TTLs in type=duration
+// format and applies them to the default TTLs.
+func parseMetadataTTL(value string) (map[string]time.Duration, error) {
+	ttl := make(map[string]time.Duration)
+	for entryType, duration := range defaultMetadataTTL {
+		ttl[entryType] = duration
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entryType, duration, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if _, exists := ttl[entryType]; !exists {
+			return nil, errors.New("Invalid metadata type: " + entryType)
+		}
+
+		d, err := time.ParseDuration(duration)
+		if err != nil || d < 0 {
+			return nil, errors.New("Invalid metadata TTL: " + entry)
+		}
+		ttl[entryType] = d
+	}
+
+	return ttl, nil
+}
+
+// Get decodes the entry for the given key into value if it exists and hasn't expired.
+func (store *metadataStore) get(entryType, key string, value interface{}) bool {
+	store.Lock()
+	defer store.Unlock()
+
+	entry, exists := store.entries[entryType+"/"+key]
+	if !exists || entry.expired(time.Now()) {
+		return false
+	}
+
+	return json.Unmarshal(entry.Value, value) == nil
+}
+
+// Put stores an entry and persists the cache.
+func (store *metadataStore) put(entryType, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := metadataEntry{Type: entryType, Value: data}
+	if ttl := store.ttl[entryType]; ttl > 0 {
+		expires := time.Now().Add(ttl)
+		entry.Expires = &expires
+	}
+
+	store.Lock()
+	defer store.Unlock()
+
+	store.entries[entryType+"/"+key] = entry
+	return store.save()
+}
+
+// Invalidate removes every entry of the given type (or all entries if the type
+// is empty) and returns the number of entries removed.
+func (store *metadataStore) invalidate(entryType string) (int, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	removed := 0
+	for key, entry := range store.entries {
+		if entryType == "" || entry.Type == entryType {
+			delete(store.entries, key)
+			removed++
+		}
+	}
+
+	return removed, store.save()
+}
+
+// Save writes the unexpired entries to the cache file. The lock must be held.
+func (store *metadataStore) save() error {
+	if store.path == "" {
+		return nil
+	}
+
+	now := time.Now()
+	for key, entry := range store.entries {
+		if entry.expired(now) {
+			delete(store.entries, key)
+		}
+	}
+
+	data, err := json.Marshal(store.entries)
+	if err != nil {
+		return err
+	}
+
+	// Replace the file atomically so a crash can't leave it half-written
+	tmp, err := os.CreateTemp(filepath.Dir(store.path), ".metadata")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), store.path)
+}
+
+// Expired checks whether the entry has expired at the given time.
+func (entry metadataEntry) expired(now time.Time) bool {
+	return entry.Expires != nil && now.After(*entry.Expires)
+}
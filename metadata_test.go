@@ -0,0 +1,76 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetadataTTL(t *testing.T) {
+	ttl, err := parseMetadataTTL("missing=1h")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{metadataRelease: 0, metadataMissing: time.Hour, metadataAlias: time.Hour}, ttl)
+
+	ttl, err = parseMetadataTTL("release=720h, missing=0s, alias=5m")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{metadataRelease: 720 * time.Hour, metadataMissing: 0, metadataAlias: 5 * time.Minute}, ttl)
+
+	_, err = parseMetadataTTL("releases=1h")
+	assert.Error(t, err)
+	_, err = parseMetadataTTL("release=-1h")
+	assert.Error(t, err)
+	_, err = parseMetadataTTL("release")
+	assert.Error(t, err)
+}
+
+func TestMetadataStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	ttl := map[string]time.Duration{metadataRelease: 0, metadataMissing: time.Hour}
+
+	store := newMetadataStore(path, ttl)
+	binary := adoptiumBinary{Architecture: "x64", Platform: "linux", Package: jdkPackage{Name: "jdk.tar.gz"}}
+	assert.NoError(t, store.put(metadataRelease, "x64_linux", binary))
+	assert.NoError(t, store.put(metadataMissing, "x64_mac", true))
+
+	var cached adoptiumBinary
+	assert.True(t, store.get(metadataRelease, "x64_linux", &cached))
+	assert.Equal(t, binary, cached)
+	assert.False(t, store.get(metadataRelease, "x64_mac", &cached))
+
+	// Entries are persisted
+	store = newMetadataStore(path, ttl)
+	assert.True(t, store.get(metadataRelease, "x64_linux", &cached))
+	var missing bool
+	assert.True(t, store.get(metadataMissing, "x64_mac", &missing))
+
+	// Entries expire
+	store.ttl = map[string]time.Duration{metadataMissing: time.Millisecond}
+	assert.NoError(t, store.put(metadataMissing, "x64_windows", true))
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, store.get(metadataMissing, "x64_windows", &missing))
+
+	// Entries can be invalidated by type
+	removed, err := store.invalidate(metadataMissing)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.False(t, newMetadataStore(path, ttl).get(metadataMissing, "x64_mac", &missing))
+	assert.True(t, newMetadataStore(path, ttl).get(metadataRelease, "x64_linux", &cached))
+
+	removed, err = store.invalidate("")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, newMetadataStore(path, ttl).get(metadataRelease, "x64_linux", &cached))
+}
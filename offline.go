@@ -0,0 +1,161 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OfflineError means that a request needs remote content in offline mode.
+type offlineError struct {
+	Resource string
+}
+
+func (e *offlineError) Error() string {
+	return e.Resource + " is not available offline"
+}
+
+// OfflineTransport is a HTTP transport which also reads file:// URLs and which
+// refuses any other URL in offline mode instead of waiting for a timeout.
+type offlineTransport struct {
+	remote http.RoundTripper
+	local  http.RoundTripper
+}
+
+// NewTransport returns the transport for upstream HTTP clients.
+func newTransport() http.RoundTripper {
+	return &offlineTransport{
+		remote: http.DefaultTransport,
+		local:  http.NewFileTransport(http.Dir("/")),
+	}
+}
+
+func (transport *offlineTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme == "file" {
+		return transport.local.RoundTrip(request)
+	}
+	if OFFLINE {
+		return nil, &offlineError{request.URL.String()}
+	}
+
+	return transport.remote.RoundTrip(request)
+}
+
+// LookupOfflineRelease finds release metadata for the given attributes in the
+// OFFLINE_INDEX file (a JSON list of releases whose package links are usually
+// file:// URLs) or else in the manifests of the runtimes in RT_CACHE.
+func lookupOfflineRelease(vendor, arch, platform, implementation, version, releaseType string) (*jdkRelease, error) {
+	releases, err := offlineReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if release.Vendor == vendor && release.Architecture == arch && release.Platform == platform &&
+			release.Implementation == implementation && release.Version == version && release.ReleaseType == releaseType {
+			return &release, nil
+		}
+	}
+
+	return nil, &offlineError{strings.Join([]string{"Java", version, implementation, "from", vendor, "for", platform, arch}, " ")}
+}
+
+// LookupOfflineAlias finds the newest offline release for a version alias like
+// latest, lts, 17 or 17-latest. Long-term support releases are recognized by
+// their feature version since the index doesn't record them.
+func lookupOfflineAlias(vendor, arch, platform, implementation, alias, releaseType string) (*jdkRelease, error) {
+	releases, err := offlineReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *jdkRelease
+	for i, release := range releases {
+		if release.Vendor != vendor || release.Architecture != arch || release.Platform != platform ||
+			release.Implementation != implementation || release.ReleaseType != releaseType {
+			continue
+		}
+
+		major, err := getMajorVersion(release.Version)
+		if err != nil || (alias == "lts" && !ltsFeature(major)) || (aliasFeature(alias) != 0 && aliasFeature(alias) != major) {
+			continue
+		}
+		if newest == nil || compareVersions(release.Version, newest.Version) > 0 {
+			newest = &releases[i]
+		}
+	}
+	if newest != nil {
+		return newest, nil
+	}
+
+	return nil, &offlineError{strings.Join([]string{"Java", alias, implementation, "from", vendor, "for", platform, arch}, " ")}
+}
+
+// OfflineReleases lists the releases in the OFFLINE_INDEX file followed by the
+// runtimes in RT_CACHE. Releases in the index without a vendor belong to
+// DEFAULT_VENDOR and releases without a release type are GA releases.
+func offlineReleases() ([]jdkRelease, error) {
+	var releases []jdkRelease
+	if OFFLINE_INDEX != "" {
+		data, err := os.ReadFile(OFFLINE_INDEX)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &releases); err != nil {
+			return nil, err
+		}
+		for i := range releases {
+			if releases[i].Vendor == "" {
+				releases[i].Vendor = DEFAULT_VENDOR
+			}
+		}
+	}
+
+	// Fall back to the runtimes which were already downloaded
+	manifests, _ := filepath.Glob(filepath.Join(RT_CACHE, "*", runtimeManifestName))
+	for _, path := range manifests {
+		if strings.HasPrefix(filepath.Base(filepath.Dir(path)), runtimeStagingPrefix) {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var manifest runtimeManifest
+		if json.Unmarshal(data, &manifest) != nil {
+			continue
+		}
+
+		releases = append(releases, jdkRelease{
+			Vendor:         manifest.Vendor,
+			Architecture:   manifest.Architecture,
+			Platform:       manifest.Platform,
+			Implementation: manifest.Implementation,
+			Version:        manifest.Version,
+			ReleaseType:    manifest.ReleaseType,
+			Package:        jdkPackage{Name: manifest.Package, Link: manifest.Link, Checksum: manifest.Checksum},
+		})
+	}
+
+	for i := range releases {
+		if releases[i].ReleaseType == "" {
+			releases[i].ReleaseType = "ga"
+		}
+	}
+
+	return releases, nil
+}
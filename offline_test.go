@@ -0,0 +1,131 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineTransport(t *testing.T) {
+	defer func(offline bool) { OFFLINE = offline }(OFFLINE)
+	OFFLINE = true
+
+	// Remote requests fail immediately
+	_, err := adoptium.Get("https://api.adoptopenjdk.net/v3/info/available_releases")
+	var offline *offlineError
+	assert.True(t, errors.As(err, &offline))
+	assert.EqualError(t, offline, "https://api.adoptopenjdk.net/v3/info/available_releases is not available offline")
+
+	// The client is told why
+	assert.Equal(t, offline.Error(), newBuildError("Failed to find target runtime", err).Reason)
+
+	// Local files can be read
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("local"), 0644))
+	response, err := adoptium.Get("file://" + filepath.ToSlash(path))
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, 200, response.StatusCode)
+}
+
+func TestLookupOfflineRelease(t *testing.T) {
+	defer func(offline bool, index, cache string, metadata *metadataStore) {
+		OFFLINE, OFFLINE_INDEX, RT_CACHE, metadataCache = offline, index, cache, metadata
+	}(OFFLINE, OFFLINE_INDEX, RT_CACHE, metadataCache)
+	OFFLINE = true
+	RT_CACHE = t.TempDir()
+	metadataCache = newMetadataStore("", defaultMetadataTTL)
+
+	// A runtime package in a local directory
+	contents, checksum := newRuntimePackage(t)
+	packagePath := filepath.Join(t.TempDir(), "indexed.zip")
+	assert.NoError(t, os.WriteFile(packagePath, contents, 0644))
+
+	OFFLINE_INDEX = filepath.Join(t.TempDir(), "index.json")
+	assert.NoError(t, os.WriteFile(OFFLINE_INDEX, []byte(`[{
+		"version": "11",
+		"architecture": "x64",
+		"os": "linux",
+		"jvm_impl": "hotspot",
+		"package": {"name": "indexed.zip", "link": "file://`+filepath.ToSlash(packagePath)+`", "checksum": "`+checksum+`"}
+	}]`), 0644))
+
+	// Releases are found in the index
+	jdk, err := lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "11", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "indexed.zip", jdk.Package.Name)
+
+	path, release, err := downloadJdk(jdk)
+	assert.NoError(t, err)
+	release()
+	assert.FileExists(t, filepath.Join(path, "release"))
+
+	// Or by scanning RT_CACHE
+	OFFLINE_INDEX = ""
+	jdk, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "11", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "indexed.zip", jdk.Package.Name)
+
+	// Other releases aren't available
+	_, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "17", "ga")
+	assert.EqualError(t, err, "Java 17 hotspot from adoptopenjdk for linux x64 is not available offline")
+	_, err = lookupJdk("temurin", "x64", "linux", "hotspot", "11", "ga")
+	assert.EqualError(t, err, "Java 11 hotspot from temurin for linux x64 is not available offline")
+
+	// Aliases resolve to the newest offline release
+	jdk, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "11-latest", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, "indexed.zip", jdk.Package.Name)
+	for _, alias := range []string{"latest", "lts"} {
+		jdk, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", alias, "ga")
+		assert.NoError(t, err)
+		assert.Equal(t, "11", jdk.Version)
+	}
+	_, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "17-latest", "ga")
+	assert.EqualError(t, err, "Java 17-latest hotspot from adoptopenjdk for linux x64 is not available offline")
+	_, err = lookupJdk("adoptopenjdk", "x64", "linux", "hotspot", "latest", "ea")
+	assert.EqualError(t, err, "Java latest hotspot from adoptopenjdk for linux x64 is not available offline")
+
+	// Remote packages aren't downloaded
+	_, _, err = downloadJdk(&jdkRelease{Vendor: "adoptopenjdk", Version: "17", Package: jdkPackage{Name: "remote.zip", Link: "https://example.com/remote.zip"}})
+	var offline *offlineError
+	assert.True(t, errors.As(err, &offline))
+}
+
+func TestFetchMavenOffline(t *testing.T) {
+	defer func(offline bool, repositories []mavenRepository) {
+		OFFLINE, MAVEN_REPOSITORIES = offline, repositories
+	}(OFFLINE, MAVEN_REPOSITORIES)
+	OFFLINE = true
+
+	local := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(local, "com", "example", "a", "1"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(local, "com", "example", "a", "1", "a-1.jar"), []byte("a"), 0644))
+
+	repositories, err := parseMavenRepositories("central=https://repo1.maven.org/maven2,local=file://" + filepath.ToSlash(local))
+	assert.NoError(t, err)
+	MAVEN_REPOSITORIES = repositories
+
+	// Only the local repository is searched
+	response, repository, err := fetchMaven(mavenArtifact{"com.example", "a", "1", "", ""}.path("jar"))
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, "local", repository.Name)
+
+	_, _, err = fetchMaven(mavenArtifact{"com.example", "b", "1", "", ""}.path("jar"))
+	assert.EqualError(t, err, "/com/example/b/1/b-1.jar is not available offline")
+}
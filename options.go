@@ -0,0 +1,113 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JlinkOptions represents the jlink plugins that can be selected per request.
+type jlinkOptions struct {
+
+	// The compression level for resources in the runtime image
+	Compress string `json:"compress"`
+
+	// Whether debug information is removed
+	StripDebug bool `json:"strip_debug"`
+
+	// Whether header files are included
+	HeaderFiles bool `json:"header_files"`
+
+	// Whether man pages are included
+	ManPages bool `json:"man_pages"`
+
+	// Whether native commands (such as bin/java) are removed
+	StripNativeCommands bool `json:"strip_native_commands"`
+}
+
+var (
+	// Compression levels accepted by every version of jlink
+	compressCheck = regexp.MustCompile(`^[0-2]$`)
+
+	// Compression levels accepted by jlink 21 and later
+	compressZipCheck = regexp.MustCompile(`^zip-[0-9]$`)
+)
+
+// DefaultJlinkOptions returns the options used when a request doesn't specify any.
+func defaultJlinkOptions() jlinkOptions {
+	return jlinkOptions{
+		Compress:   "1",
+		StripDebug: true,
+	}
+}
+
+// ParseJlinkOptions reads jlink options from the query string of a request.
+func parseJlinkOptions(context *gin.Context) (jlinkOptions, error) {
+	options := defaultJlinkOptions()
+	options.Compress = context.DefaultQuery("compress", options.Compress)
+
+	for name, value := range map[string]*bool{
+		"strip_debug":           &options.StripDebug,
+		"header_files":          &options.HeaderFiles,
+		"man_pages":             &options.ManPages,
+		"strip_native_commands": &options.StripNativeCommands,
+	} {
+		if q, exists := context.GetQuery(name); exists {
+			b, err := strconv.ParseBool(q)
+			if err != nil {
+				return options, errors.New("Invalid value for " + name + " option")
+			}
+			*value = b
+		}
+	}
+
+	return options, nil
+}
+
+// Validate checks that the options are supported by the given major version of jlink.
+func (options jlinkOptions) validate(majorVersion int) error {
+	switch {
+	case compressCheck.MatchString(options.Compress):
+	case compressZipCheck.MatchString(options.Compress):
+		if majorVersion < 21 {
+			return errors.New("Compression level " + options.Compress + " requires Java 21 or later")
+		}
+	default:
+		return errors.New("Valid compression levels: [0, 1, 2, zip-0...zip-9]")
+	}
+
+	return nil
+}
+
+// Args returns the jlink command line arguments for the options.
+func (options jlinkOptions) args() []string {
+	args := []string{"--compress=" + options.Compress}
+
+	if options.StripDebug {
+		args = append(args, "--strip-debug")
+	}
+	if !options.HeaderFiles {
+		args = append(args, "--no-header-files")
+	}
+	if !options.ManPages {
+		args = append(args, "--no-man-pages")
+	}
+	if options.StripNativeCommands {
+		args = append(args, "--strip-native-commands")
+	}
+
+	return args
+}
@@ -0,0 +1,66 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJlinkOptionsValidate(t *testing.T) {
+	options := defaultJlinkOptions()
+	assert.NoError(t, options.validate(9))
+	assert.NoError(t, options.validate(21))
+
+	options.Compress = "zip-6"
+	assert.Error(t, options.validate(17))
+	assert.NoError(t, options.validate(21))
+
+	options.Compress = "3"
+	assert.Error(t, options.validate(11))
+
+	options.Compress = "zip-10"
+	assert.Error(t, options.validate(21))
+}
+
+func TestJlinkOptionsArgs(t *testing.T) {
+	assert.Equal(t, []string{"--compress=1", "--strip-debug", "--no-header-files", "--no-man-pages"}, defaultJlinkOptions().args())
+
+	assert.Equal(t, []string{"--compress=zip-6", "--strip-native-commands"}, jlinkOptions{
+		Compress:            "zip-6",
+		HeaderFiles:         true,
+		ManPages:            true,
+		StripNativeCommands: true,
+	}.args())
+}
+
+func TestParseJlinkOptions(t *testing.T) {
+	newContext := func(url string) *gin.Context {
+		context, _ := gin.CreateTestContext(httptest.NewRecorder())
+		context.Request = httptest.NewRequest("GET", url, nil)
+		return context
+	}
+
+	options, err := parseJlinkOptions(newContext("/runtime/x64/linux/11.0.8+10"))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultJlinkOptions(), options)
+
+	options, err = parseJlinkOptions(newContext("/runtime/x64/linux/11.0.8+10?compress=0&strip_debug=false&man_pages=true"))
+	assert.NoError(t, err)
+	assert.Equal(t, jlinkOptions{Compress: "0", ManPages: true}, options)
+
+	_, err = parseJlinkOptions(newContext("/runtime/x64/linux/11.0.8+10?header_files=abc"))
+	assert.Error(t, err)
+}
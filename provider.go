@@ -0,0 +1,91 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// JdkRelease describes a JDK package from a provider.
+type jdkRelease struct {
+	Vendor         string     `json:"vendor"`
+	Architecture   string     `json:"architecture"`
+	Platform       string     `json:"os"`
+	Implementation string     `json:"jvm_impl"`
+	Version        string     `json:"version"`
+	ReleaseType    string     `json:"release_type"`
+	Package        jdkPackage `json:"package"`
+}
+
+// Mirrors the Adoptium "Package" schema
+type jdkPackage struct {
+	Name         string `json:"name" binding:"required"`
+	Link         string `json:"link" binding:"required"`
+	Checksum     string `json:"checksum"`
+	ChecksumLink string `json:"checksum_link"`
+}
+
+// JdkProvider resolves and fetches the JDKs of a vendor.
+type jdkProvider interface {
+
+	// Lookup finds the JDK with the given attributes. The release type is
+	// either ga or ea.
+	lookup(arch, platform, implementation, version, releaseType string) (*jdkRelease, error)
+
+	// Download makes a JDK available locally and returns the path to its home
+	// directory. The JDK is kept until the returned release function is called.
+	download(release *jdkRelease) (string, func(), error)
+}
+
+// The JDK providers by vendor name
+var jdkProviders = map[string]jdkProvider{
+	"adoptopenjdk": &adoptiumProvider{vendor: "adoptopenjdk", api: "https://api.adoptopenjdk.net"},
+	"temurin":      &adoptiumProvider{vendor: "temurin", api: "https://api.adoptium.net"},
+}
+
+// JdkVendors returns the names of the available JDK providers.
+func jdkVendors() []string {
+	var vendors []string
+	for vendor := range jdkProviders {
+		vendors = append(vendors, vendor)
+	}
+	sort.Strings(vendors)
+
+	return vendors
+}
+
+// InvalidVendor returns the error for an unknown vendor.
+func invalidVendor() error {
+	return errors.New("Valid vendors: [" + strings.Join(jdkVendors(), ", ") + "]")
+}
+
+// LookupJdk finds the JDK with the given attributes from a vendor.
+func lookupJdk(vendor, arch, platform, implementation, version, releaseType string) (*jdkRelease, error) {
+	provider, exists := jdkProviders[vendor]
+	if !exists {
+		return nil, invalidVendor()
+	}
+
+	return provider.lookup(arch, platform, implementation, version, releaseType)
+}
+
+// DownloadJdk makes a JDK available locally through its vendor's provider.
+func downloadJdk(release *jdkRelease) (string, func(), error) {
+	provider, exists := jdkProviders[release.Vendor]
+	if !exists {
+		return "", nil, invalidVendor()
+	}
+
+	return provider.download(release)
+}
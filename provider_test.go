@@ -0,0 +1,87 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupJdk(t *testing.T) {
+	defer func(metadata *metadataStore) { metadataCache = metadata }(metadataCache)
+	metadataCache = newMetadataStore("", defaultMetadataTTL)
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.String())
+		if r.URL.Query().Get("os") != "linux" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"binaries": [{"architecture": "x64", "os": "linux", "jvm_impl": "hotspot", "package": {"name": "jdk.tar.gz", "link": "https://example.com/jdk.tar.gz"}}]}]`))
+	}))
+	defer server.Close()
+
+	defer func(providers map[string]jdkProvider) { jdkProviders = providers }(jdkProviders)
+	jdkProviders = map[string]jdkProvider{"example": &adoptiumProvider{vendor: "example", api: server.URL}}
+
+	jdk, err := lookupJdk("example", "x64", "linux", "hotspot", "17.0.1+12", "ga")
+	assert.NoError(t, err)
+	assert.Equal(t, &jdkRelease{
+		Vendor:         "example",
+		Architecture:   "x64",
+		Platform:       "linux",
+		Implementation: "hotspot",
+		Version:        "17.0.1+12",
+		ReleaseType:    "ga",
+		Package:        jdkPackage{Name: "jdk.tar.gz", Link: "https://example.com/jdk.tar.gz"},
+	}, jdk)
+	assert.Equal(t, []string{"/v3/assets/version/17.0.1+12?jvm_impl=hotspot&os=linux&architecture=x64&release_type=ga"}, queries)
+
+	// Releases are cached per vendor
+	_, err = lookupJdk("example", "x64", "linux", "hotspot", "17.0.1+12", "ga")
+	assert.NoError(t, err)
+	assert.Len(t, queries, 1)
+
+	_, err = lookupJdk("example", "x64", "mac", "hotspot", "17.0.1+12", "ga")
+	assert.EqualError(t, err, "No release found")
+
+	// Unknown vendors
+	_, err = lookupJdk("oracle", "x64", "linux", "hotspot", "17.0.1+12", "ga")
+	assert.EqualError(t, err, "Valid vendors: [example]")
+	_, _, err = downloadJdk(&jdkRelease{Vendor: "oracle"})
+	assert.EqualError(t, err, "Valid vendors: [example]")
+}
+
+func TestValidateVendor(t *testing.T) {
+	req := runtimeRequest{
+		Arch:           "x64",
+		Platform:       "linux",
+		Version:        "17",
+		Implementation: "hotspot",
+		Modules:        []string{"java.base"},
+		Options:        defaultJlinkOptions(),
+	}
+
+	// The vendor defaults to DEFAULT_VENDOR
+	assert.NoError(t, validateRequest(&req))
+	assert.Equal(t, DEFAULT_VENDOR, req.Vendor)
+
+	req.Vendor = "temurin"
+	assert.NoError(t, validateRequest(&req))
+
+	req.Vendor = "oracle"
+	assert.EqualError(t, validateRequest(&req), "Valid vendors: [adoptopenjdk, temurin]")
+}
@@ -0,0 +1,316 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The name of the manifest which marks a completely extracted runtime
+const runtimeManifestName = ".manifest.json"
+
+// The prefix of staging directories for runtimes being extracted
+const runtimeStagingPrefix = ".partial-"
+
+// RuntimeManifest describes a runtime in the cache directory.
+type runtimeManifest struct {
+	Package        string    `json:"package"`
+	Link           string    `json:"link"`
+	Checksum       string    `json:"checksum"`
+	Vendor         string    `json:"vendor"`
+	Architecture   string    `json:"architecture"`
+	Platform       string    `json:"os"`
+	Implementation string    `json:"jvm_impl"`
+	Version        string    `json:"version"`
+	ReleaseType    string    `json:"release_type"`
+	Size           int64     `json:"size"`
+	Extracted      time.Time `json:"extracted"`
+}
+
+// RuntimeComplete checks whether a cached runtime was completely extracted.
+func runtimeComplete(runtimePath string) bool {
+	_, err := os.Stat(filepath.Join(runtimePath, runtimeManifestName))
+	return err == nil
+}
+
+// TouchRuntime records an access to a cached runtime as the modification time
+// of its manifest.
+func touchRuntime(runtimePath string) {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(runtimePath, runtimeManifestName), now, now); err != nil {
+		log.Println(err)
+	}
+}
+
+// WriteRuntimeManifest marks an extracted runtime as complete.
+func writeRuntimeManifest(runtimePath string, manifest runtimeManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(runtimePath, runtimeManifestName), data, 0644)
+}
+
+// CleanRuntimeCache removes the staging directories of extractions which were
+// interrupted by a crash. Other directories without a manifest are kept since
+// they may have been extracted by an older version or by an operator, and
+// they're replaced when their runtime is downloaded again. It must only be
+// called when no downloads are in progress.
+func cleanRuntimeCache() error {
+	entries, err := os.ReadDir(RT_CACHE)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(RT_CACHE, entry.Name())
+		if strings.HasPrefix(entry.Name(), runtimeStagingPrefix) {
+			log.Println("Removing incomplete runtime:", path)
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// The number of builds using each cached runtime
+var runtimeUsers = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// AcquireRuntime prevents a cached runtime from being evicted until the
+// returned release function is called.
+func acquireRuntime(runtimePath string) func() {
+	runtimePath = filepath.Clean(runtimePath)
+
+	runtimeUsers.Lock()
+	runtimeUsers.count[runtimePath]++
+	runtimeUsers.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			runtimeUsers.Lock()
+			defer runtimeUsers.Unlock()
+
+			if runtimeUsers.count[runtimePath]--; runtimeUsers.count[runtimePath] == 0 {
+				delete(runtimeUsers.count, runtimePath)
+			}
+		})
+	}
+}
+
+// RuntimeHome returns the JDK directory in a cached runtime. It's usually named
+// after the version, but otherwise it's the only directory in the runtime.
+func runtimeHome(runtimePath, version string) string {
+	jdkPath := filepath.Join(runtimePath, "jdk-"+version)
+	if _, err := os.Stat(jdkPath); err == nil {
+		return jdkPath
+	}
+
+	entries, err := os.ReadDir(runtimePath)
+	if err != nil {
+		return jdkPath
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		return filepath.Join(runtimePath, dirs[0])
+	}
+
+	return jdkPath
+}
+
+// CachedRuntime is a completely extracted runtime in the cache directory.
+type cachedRuntime struct {
+	path     string
+	size     int64
+	accessed time.Time
+}
+
+// ListRuntimes returns the completely extracted runtimes in the cache directory.
+func listRuntimes() ([]cachedRuntime, error) {
+	entries, err := os.ReadDir(RT_CACHE)
+	if err != nil {
+		return nil, err
+	}
+
+	var runtimes []cachedRuntime
+	for _, entry := range entries {
+		path := filepath.Join(RT_CACHE, entry.Name())
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), runtimeStagingPrefix) {
+			continue
+		}
+
+		manifestPath := filepath.Join(path, runtimeManifestName)
+		info, err := os.Stat(manifestPath)
+		if err != nil {
+			continue
+		}
+
+		var manifest runtimeManifest
+		if data, err := os.ReadFile(manifestPath); err == nil {
+			_ = json.Unmarshal(data, &manifest)
+		}
+		if manifest.Size == 0 {
+			if manifest.Size, err = directorySize(path); err != nil {
+				return nil, err
+			}
+		}
+
+		runtimes = append(runtimes, cachedRuntime{path, manifest.Size, info.ModTime()})
+	}
+
+	return runtimes, nil
+}
+
+// Only one eviction runs at a time
+var evictionLock sync.Mutex
+
+// EvictRuntimes removes the least recently used runtimes until the cache is
+// within RT_CACHE_MAX_SIZE and RT_CACHE_MIN_FREE. Runtimes which are in use
+// are never removed.
+func evictRuntimes() error {
+	if RT_CACHE_MAX_SIZE == 0 && RT_CACHE_MIN_FREE == 0 {
+		return nil
+	}
+
+	evictionLock.Lock()
+	defer evictionLock.Unlock()
+
+	runtimes, err := listRuntimes()
+	if err != nil {
+		return err
+	}
+	sort.Slice(runtimes, func(i, j int) bool {
+		return runtimes[i].accessed.Before(runtimes[j].accessed)
+	})
+
+	var size int64
+	for _, runtime := range runtimes {
+		size += runtime.size
+	}
+
+	// The free space can't be checked on every platform
+	free, freeErr := cacheFree()
+
+	for _, runtime := range runtimes {
+		tooLarge := RT_CACHE_MAX_SIZE > 0 && size > RT_CACHE_MAX_SIZE
+		tooFull := RT_CACHE_MIN_FREE > 0 && freeErr == nil && free < RT_CACHE_MIN_FREE
+		if !tooLarge && !tooFull {
+			break
+		}
+
+		// Hold the lock while removing so the runtime can't be acquired meanwhile
+		runtimeUsers.Lock()
+		if runtimeUsers.count[runtime.path] > 0 {
+			runtimeUsers.Unlock()
+			continue
+		}
+
+		log.Println("Evicting runtime:", runtime.path)
+		err := os.RemoveAll(runtime.path)
+		runtimeUsers.Unlock()
+		if err != nil {
+			return err
+		}
+
+		size -= runtime.size
+		free += runtime.size
+	}
+
+	return nil
+}
+
+// CacheFree returns the free space in bytes on the RT_CACHE filesystem.
+func cacheFree() (int64, error) {
+	if LOCAL_PLATFORM == "windows" {
+		return 0, errors.New("Free space can't be determined on Windows")
+	}
+
+	out, err := exec.Command("df", "-B1", "--output=avail", RT_CACHE).Output()
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return 0, errors.New("Unexpected output from df: " + string(out))
+	}
+	return strconv.ParseInt(fields[1], 10, 64)
+}
+
+// DirectorySize returns the total size of the files in a directory.
+func directorySize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size, err
+}
+
+// MakeToolsExecutable adds the execute permission to the files in the bin
+// directories of an extracted runtime since some packages don't preserve it.
+// Files which are already executable are left unchanged.
+func makeToolsExecutable(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || filepath.Base(filepath.Dir(path)) != "bin" {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0100 != 0 {
+			return nil
+		}
+		return os.Chmod(path, info.Mode().Perm()|0111)
+	})
+}
@@ -0,0 +1,100 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanRuntimeCache(t *testing.T) {
+	defer func(cache string) { RT_CACHE = cache }(RT_CACHE)
+	RT_CACHE = t.TempDir()
+
+	complete := filepath.Join(RT_CACHE, "complete")
+	legacy := filepath.Join(RT_CACHE, "legacy")
+	staging := filepath.Join(RT_CACHE, runtimeStagingPrefix+"123")
+	for _, dir := range []string{complete, legacy, staging} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "jdk-11"), os.ModePerm))
+	}
+	assert.NoError(t, writeRuntimeManifest(complete, runtimeManifest{Package: "complete.tar.gz"}))
+	assert.NoError(t, writeRuntimeManifest(staging, runtimeManifest{Package: "staging.tar.gz"}))
+	assert.NoError(t, os.WriteFile(filepath.Join(RT_CACHE, "metadata.json"), []byte("{}"), 0644))
+
+	assert.NoError(t, cleanRuntimeCache())
+	assert.True(t, runtimeComplete(complete))
+	assert.NoDirExists(t, staging)
+
+	// Runtimes without a manifest from older versions are kept
+	assert.DirExists(t, filepath.Join(legacy, "jdk-11"))
+	assert.FileExists(t, filepath.Join(RT_CACHE, "metadata.json"))
+}
+
+func TestEvictRuntimes(t *testing.T) {
+	defer func(cache string, maxSize, minFree int64) {
+		RT_CACHE, RT_CACHE_MAX_SIZE, RT_CACHE_MIN_FREE = cache, maxSize, minFree
+	}(RT_CACHE, RT_CACHE_MAX_SIZE, RT_CACHE_MIN_FREE)
+	RT_CACHE = t.TempDir()
+	RT_CACHE_MIN_FREE = 0
+
+	// Four runtimes of 100 bytes, accessed in order
+	now := time.Now()
+	var paths []string
+	for i, name := range []string{"a", "b", "c", "d"} {
+		path := filepath.Join(RT_CACHE, name)
+		assert.NoError(t, os.MkdirAll(path, os.ModePerm))
+		assert.NoError(t, writeRuntimeManifest(path, runtimeManifest{Package: name, Size: 100}))
+		accessed := now.Add(time.Duration(i-10) * time.Minute)
+		assert.NoError(t, os.Chtimes(filepath.Join(path, runtimeManifestName), accessed, accessed))
+		paths = append(paths, path)
+	}
+
+	// Accessing a runtime makes it the most recently used
+	touchRuntime(paths[0])
+
+	// Runtimes in use are never evicted
+	release := acquireRuntime(paths[1])
+
+	RT_CACHE_MAX_SIZE = 250
+	assert.NoError(t, evictRuntimes())
+	assert.True(t, runtimeComplete(paths[0]))
+	assert.True(t, runtimeComplete(paths[1]))
+	assert.NoDirExists(t, paths[2])
+	assert.NoDirExists(t, paths[3])
+
+	release()
+	release()
+	assert.NoError(t, evictRuntimes())
+	assert.True(t, runtimeComplete(paths[0]))
+	assert.True(t, runtimeComplete(paths[1]))
+
+	RT_CACHE_MAX_SIZE = 150
+	assert.NoError(t, evictRuntimes())
+	assert.True(t, runtimeComplete(paths[0]))
+	assert.NoDirExists(t, paths[1])
+}
+
+func TestRuntimeHome(t *testing.T) {
+	runtime := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(runtime, "jdk-23+10"), os.ModePerm))
+	assert.NoError(t, writeRuntimeManifest(runtime, runtimeManifest{Package: "jdk.tar.gz"}))
+
+	// The JDK directory isn't always named after the version
+	assert.Equal(t, filepath.Join(runtime, "jdk-23+10"), runtimeHome(runtime, "23-ea+10"))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(runtime, "jdk-23-ea+10"), os.ModePerm))
+	assert.Equal(t, filepath.Join(runtime, "jdk-23-ea+10"), runtimeHome(runtime, "23-ea+10"))
+}
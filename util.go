@@ -12,8 +12,10 @@
 package main
 
 import (
+	"errors"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -29,9 +31,81 @@ func getMajorVersion(version string) (int, error) {
 		version = version[:i]
 	}
 
+	if i := strings.Index(version, "-"); i != -1 {
+		version = version[:i]
+	}
+
 	return strconv.Atoi(version)
 }
 
+// IsVersionAlias checks whether a version is an alias like latest, lts, 17 or
+// 17-latest rather than an exact version.
+func isVersionAlias(version string) bool {
+	return aliasCheck.MatchString(version)
+}
+
+// AliasFeature returns the feature version of an alias like 17 or 17-latest,
+// or zero for latest and lts.
+func aliasFeature(alias string) int {
+	feature, _ := strconv.Atoi(strings.TrimSuffix(alias, "-latest"))
+	return feature
+}
+
+// ReleaseTypeOf returns ea for early-access versions like 22-ea+5 and ga for
+// any other version. Aliases like 17-latest are GA versions even though they
+// look like early-access versions.
+func releaseTypeOf(version string) string {
+	if !isVersionAlias(version) && eaVersionCheck.MatchString(version) {
+		return "ea"
+	}
+	return "ga"
+}
+
+// CompareVersions compares two exact Java versions like 17.0.1+12 or 22-ea+5
+// and returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	aVersion, aBuild, _ := strings.Cut(a, "+")
+	bVersion, bBuild, _ := strings.Cut(b, "+")
+	aVersion, aPre, _ := strings.Cut(aVersion, "-")
+	bVersion, bPre, _ := strings.Cut(bVersion, "-")
+
+	if c := compareNumbers(aVersion, bVersion); c != 0 {
+		return c
+	}
+
+	// An early-access build comes before the release
+	if aPre == "" && bPre != "" {
+		return 1
+	}
+	if aPre != "" && bPre == "" {
+		return -1
+	}
+	return compareNumbers(aBuild, bBuild)
+}
+
+// CompareNumbers compares dot-separated numbers where missing numbers are zero.
+func compareNumbers(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+
+	return 0
+}
+
 var parseModules = regexp.MustCompile(`requires[\s]*(transitive)?[\s]+([\w\.]+)[\s]*;`)
 
 // ParseModuleInfo extracts the module dependencies from a module-info.java file.
@@ -44,6 +118,34 @@ func parseModuleInfo(file string) []string {
 	return modules
 }
 
+// The binary unit suffixes accepted by parseByteSize
+var byteSizeUnits = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseByteSize parses a number of bytes with an optional K, M, G or T suffix
+// (like "512M" or "20G").
+func parseByteSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	number := strings.TrimRight(size, "KMGT")
+
+	unit, exists := byteSizeUnits[size[len(number):]]
+	if !exists {
+		return 0, errors.New("Invalid size: " + size)
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("Invalid size: " + size)
+	}
+
+	return n * unit, nil
+}
+
 // NewTemporaryFile returns a new temporary file and its parent directory.
 func newTemporaryFile(filename string) (string, string) {
 	dir := TMP + string(os.PathSeparator) + strconv.Itoa(rand.Int())
@@ -57,3 +159,25 @@ func newTemporaryDirectory(dirname string) (string, string) {
 	_ = os.MkdirAll(dir+"/"+dirname, os.ModePerm)
 	return dir + string(os.PathSeparator) + dirname, dir
 }
+
+// LocalTool returns the path to an executable in the bin directory of a local
+// runtime. The runtime is never modified since it might be a shared local JDK.
+func localTool(jdk, name string) (string, error) {
+	var tool string
+
+	// Build command according to local platform
+	switch LOCAL_PLATFORM {
+	case "mac":
+		tool = filepath.FromSlash(jdk + "/Contents/Home/bin/" + name)
+	case "windows":
+		tool = filepath.FromSlash(jdk + "/bin/" + name + ".exe")
+	default:
+		tool = filepath.FromSlash(jdk + "/bin/" + name)
+	}
+
+	if _, err := os.Stat(tool); err != nil {
+		return "", err
+	}
+
+	return tool, nil
+}
@@ -34,10 +34,32 @@ func TestGetMajorVersion(t *testing.T) {
 	assert.Equal(t, 9, m)
 	assert.NoError(t, err)
 
+	m, err = getMajorVersion("17-latest")
+	assert.Equal(t, 17, m)
+	assert.NoError(t, err)
+
 	m, err = getMajorVersion("9a.1")
 	assert.Error(t, err)
 }
 
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("17", "17.0.0"))
+	assert.Equal(t, -1, compareVersions("17+35", "17.0.1+12"))
+	assert.Equal(t, 1, compareVersions("17.0.10+7", "17.0.9+9"))
+	assert.Equal(t, 1, compareVersions("11.0.8+10.1", "11.0.8+10"))
+	assert.Equal(t, -1, compareVersions("11.0.8", "11.0.8+10"))
+
+	// Early-access builds come before the release
+	assert.Equal(t, -1, compareVersions("22-ea+5", "22-ea+6"))
+	assert.Equal(t, -1, compareVersions("22-ea+36", "22+36"))
+	assert.Equal(t, 1, compareVersions("22-ea+5", "21.0.2+13"))
+
+	assert.Equal(t, "ea", releaseTypeOf("22-ea+5"))
+	assert.Equal(t, "ga", releaseTypeOf("22+36"))
+	assert.Equal(t, "ga", releaseTypeOf("latest"))
+	assert.Equal(t, "ga", releaseTypeOf("17-latest"))
+}
+
 func TestParseModuleInfo(t *testing.T) {
 	assert.Equal(t, []string{"org.slf4j"}, parseModuleInfo(`
 		module com.abc {
@@ -58,3 +80,23 @@ func TestParseModuleInfo(t *testing.T) {
 		}
 	`))
 }
+
+func TestParseByteSize(t *testing.T) {
+	for size, expected := range map[string]int64{
+		"0":     0,
+		"1024":  1024,
+		"512K":  512 << 10,
+		"512m":  512 << 20,
+		" 20G ": 20 << 30,
+		"1T":    1 << 40,
+	} {
+		n, err := parseByteSize(size)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, n)
+	}
+
+	for _, size := range []string{"", "G", "1GB", "-1", "1.5G", "KM"} {
+		_, err := parseByteSize(size)
+		assert.Error(t, err, size)
+	}
+}
@@ -0,0 +1,141 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WarmTarget identifies a runtime to download into RT_CACHE ahead of time.
+type warmTarget struct {
+	Arch           string `json:"arch"`
+	Platform       string `json:"os"`
+	Version        string `json:"version"`
+	Implementation string `json:"implementation"`
+	Vendor         string `json:"vendor"`
+}
+
+// String returns the target in arch/os/version/implementation/vendor format.
+func (target warmTarget) String() string {
+	return strings.Join([]string{target.Arch, target.Platform, target.Version, target.Implementation, target.Vendor}, "/")
+}
+
+// ParseWarmTargets parses runtimes in arch/os/version[/implementation[/vendor]]
+// format. The implementation defaults to hotspot and the vendor to DEFAULT_VENDOR.
+func parseWarmTargets(values []string) ([]warmTarget, error) {
+	var targets []warmTarget
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		fields := strings.Split(value, "/")
+		if len(fields) == 3 {
+			fields = append(fields, "hotspot")
+		}
+		if len(fields) == 4 {
+			fields = append(fields, DEFAULT_VENDOR)
+		}
+		if len(fields) != 5 || !archCheck.MatchString(fields[0]) || !platformCheck.MatchString(fields[1]) ||
+			(!versionCheck.MatchString(fields[2]) && !isVersionAlias(fields[2]) && !eaVersionCheck.MatchString(fields[2])) || (fields[3] != "hotspot" && fields[3] != "openj9") || jdkProviders[fields[4]] == nil {
+			return nil, errors.New("Invalid runtime (expected arch/os/version[/implementation[/vendor]]): " + value)
+		}
+
+		targets = append(targets, warmTarget{fields[0], fields[1], fields[2], fields[3], fields[4]})
+	}
+
+	return targets, nil
+}
+
+// The progress of warming RT_CACHE
+var warming = struct {
+	sync.Mutex
+	total  int
+	done   int
+	failed []string
+}{}
+
+// WarmRuntimes downloads the target runtime of each target along with the
+// matching local runtime that jlink runs from.
+func warmRuntimes(targets []warmTarget) error {
+	warming.Lock()
+	warming.total += len(targets)
+	warming.Unlock()
+
+	var failed int
+	for i, target := range targets {
+		log.Printf("Warming runtime %d/%d: %s", i+1, len(targets), target)
+
+		err := warmRuntime(target)
+
+		warming.Lock()
+		warming.done++
+		if err != nil {
+			failed++
+			warming.failed = append(warming.failed, target.String())
+		}
+		warming.Unlock()
+
+		if err != nil {
+			log.Printf("Failed to warm runtime %s: %v", target, err)
+		}
+	}
+
+	log.Printf("Warmed %d/%d runtimes", len(targets)-failed, len(targets))
+	if failed > 0 {
+		return fmt.Errorf("Failed to warm %d runtimes", failed)
+	}
+	return nil
+}
+
+// WarmRuntime downloads the target and local runtimes for a target.
+func warmRuntime(target warmTarget) error {
+	releaseType := releaseTypeOf(target.Version)
+	jdk, err := lookupJdk(target.Vendor, target.Arch, target.Platform, target.Implementation, target.Version, releaseType)
+	if err != nil {
+		return err
+	}
+
+	// The local runtime must have the version which an alias resolved to
+	local, err := lookupJdk(target.Vendor, LOCAL_ARCH, LOCAL_PLATFORM, target.Implementation, jdk.Version, releaseType)
+	if err != nil {
+		return err
+	}
+
+	for _, jdk := range []*jdkRelease{jdk, local} {
+		_, release, err := downloadJdk(jdk)
+		if err != nil {
+			return err
+		}
+		release()
+	}
+
+	return nil
+}
+
+// WarmStatus reports the progress of warming RT_CACHE for the status endpoint.
+func warmStatus() gin.H {
+	warming.Lock()
+	defer warming.Unlock()
+
+	return gin.H{
+		"total":  warming.total,
+		"done":   warming.done,
+		"failed": append([]string{}, warming.failed...),
+	}
+}
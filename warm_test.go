@@ -0,0 +1,74 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWarmTargets(t *testing.T) {
+	targets, err := parseWarmTargets([]string{"x64/linux/11.0.8+10", " aarch64/mac/lts/openj9/temurin", "x64/linux/23-ea+10", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []warmTarget{
+		{"x64", "linux", "11.0.8+10", "hotspot", "adoptopenjdk"},
+		{"aarch64", "mac", "lts", "openj9", "temurin"},
+		{"x64", "linux", "23-ea+10", "hotspot", "adoptopenjdk"},
+	}, targets)
+
+	for _, value := range []string{"x64/linux", "x64/linux/11/graal", "x99/linux/11", "x64/dos/11", "x64/linux/abc", "x64/linux/17-", "x64/linux/11/hotspot/oracle", "x64/linux/11/hotspot/temurin/extra"} {
+		_, err := parseWarmTargets([]string{value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestWarmRuntimes(t *testing.T) {
+	defer func(cache string, metadata *metadataStore) { RT_CACHE, metadataCache = cache, metadata }(RT_CACHE, metadataCache)
+	RT_CACHE = t.TempDir()
+	metadataCache = newMetadataStore("", defaultMetadataTTL)
+
+	contents, checksum := newRuntimePackage(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	}))
+	defer server.Close()
+
+	// Avoid querying the Adoptium API for the target and local runtimes
+	for key, name := range map[string]string{
+		"adoptopenjdk_aarch64_linux_hotspot_11.0.8+10_ga":                             "target.zip",
+		"adoptopenjdk_" + LOCAL_ARCH + "_" + LOCAL_PLATFORM + "_hotspot_11.0.8+10_ga": "local.zip",
+		"adoptopenjdk_aarch64_linux_hotspot_12.0.2+10_ga":                             "other.zip",
+	} {
+		assert.NoError(t, metadataCache.put(metadataRelease, key, adoptiumBinary{
+			Package: jdkPackage{Name: name, Link: server.URL, Checksum: checksum},
+		}))
+	}
+
+	// The local runtime for Java 12 doesn't exist
+	assert.NoError(t, metadataCache.put(metadataMissing, "adoptopenjdk_"+LOCAL_ARCH+"_"+LOCAL_PLATFORM+"_hotspot_12.0.2+10_ga", true))
+
+	err := warmRuntimes([]warmTarget{
+		{"aarch64", "linux", "11.0.8+10", "hotspot", "adoptopenjdk"},
+		{"aarch64", "linux", "12.0.2+10", "hotspot", "adoptopenjdk"},
+	})
+	assert.Error(t, err)
+	assert.True(t, runtimeComplete(filepath.Join(RT_CACHE, "adoptopenjdk-target")))
+	assert.True(t, runtimeComplete(filepath.Join(RT_CACHE, "adoptopenjdk-local")))
+
+	status := warmStatus()
+	assert.Equal(t, gin.H{"total": 2, "done": 2, "failed": []string{"aarch64/linux/12.0.2+10/hotspot/adoptopenjdk"}}, status)
+}